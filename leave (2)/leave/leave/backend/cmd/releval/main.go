@@ -0,0 +1,200 @@
+// Command releval runs judged queries through the search pipeline and reports
+// NDCG, MRR and precision, optionally diffing two ranking profiles.
+//
+//	go run ./cmd/releval -judgments judgments.tsv -docs docs.jsonl -profiles ranking.json -profile default -compare fresh
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+
+	"search-engine-backend/internal/config"
+	"search-engine-backend/internal/ranking"
+	"search-engine-backend/internal/releval"
+	"search-engine-backend/internal/search"
+)
+
+func main() {
+	judgmentsPath := flag.String("judgments", "", "judgment file: query<TAB>url<TAB>grade")
+	engineName := flag.String("engine", "memory", "backend engine: memory or es")
+	docsPath := flag.String("docs", "", "JSON lines of documents to index into the memory engine")
+	esURL := flag.String("es-url", "", "Elasticsearch URL (defaults to ELASTICSEARCH_URL)")
+	profilesPath := flag.String("profiles", "", "ranking profiles file (defaults to RANKING_PROFILES_PATH)")
+	profile := flag.String("profile", "", "ranking profile to evaluate (empty for the default)")
+	compare := flag.String("compare", "", "second ranking profile to diff against -profile")
+	k := flag.Int("k", 10, "cutoff for NDCG and precision")
+	top := flag.Int("top", 10, "number of per-query changes to print when comparing")
+	embeddingDims := flag.Int("embedding-dims", 0, "enable hybrid retrieval with a hashing embedder of this size")
+	flag.Parse()
+
+	if *judgmentsPath == "" {
+		log.Fatal("-judgments is required")
+	}
+
+	cfg := config.Load()
+	if *esURL != "" {
+		cfg.ElasticsearchURL = *esURL
+	}
+	if *profilesPath != "" {
+		cfg.RankingProfilesPath = *profilesPath
+	}
+
+	f, err := os.Open(*judgmentsPath)
+	if err != nil {
+		log.Fatalf("Failed to open judgments: %v", err)
+	}
+	judgments, err := releval.LoadJudgments(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("Failed to load judgments: %v", err)
+	}
+
+	profiles, err := ranking.NewStore(cfg.RankingProfilesPath)
+	if err != nil {
+		log.Fatalf("Failed to load ranking profiles: %v", err)
+	}
+
+	ctx := context.Background()
+	engine, err := newEngine(cfg, *engineName)
+	if err != nil {
+		log.Fatalf("Failed to create %s engine: %v", *engineName, err)
+	}
+	svc := search.NewServiceWithEngine(cfg, nil, profiles, engine)
+	if *embeddingDims > 0 {
+		svc.SetEmbedder(search.NewHashingEmbedder(*embeddingDims))
+	}
+	if *engineName == "memory" {
+		if *docsPath == "" {
+			log.Fatal("-docs is required for the memory engine")
+		}
+		if err := loadDocuments(ctx, svc, *docsPath); err != nil {
+			log.Fatalf("Failed to load documents: %v", err)
+		}
+	}
+
+	base, err := evaluate(ctx, svc, judgments, *profile, *k)
+	if err != nil {
+		log.Fatalf("Evaluation failed: %v", err)
+	}
+	fmt.Printf("queries: %d  k: %d\n\n", len(judgments), *k)
+	fmt.Printf("%-16s %8s %8s %8s\n", "profile", "ndcg", "mrr", "p@k")
+	printMean(label(*profile), base.Mean)
+
+	if *compare == "" {
+		return
+	}
+	other, err := evaluate(ctx, svc, judgments, *compare, *k)
+	if err != nil {
+		log.Fatalf("Evaluation failed: %v", err)
+	}
+	printMean(label(*compare), other.Mean)
+	printMean("delta", releval.Metrics{
+		NDCG:      other.Mean.NDCG - base.Mean.NDCG,
+		MRR:       other.Mean.MRR - base.Mean.MRR,
+		Precision: other.Mean.Precision - base.Mean.Precision,
+	})
+	printChanges(base, other, *top)
+}
+
+func newEngine(cfg *config.Config, name string) (search.Engine, error) {
+	switch name {
+	case "es":
+		return search.NewESEngine(cfg)
+	case "memory":
+		return search.NewMemoryEngine(), nil
+	default:
+		return nil, fmt.Errorf("unknown engine %q", name)
+	}
+}
+
+// loadDocuments indexes JSON lines through the service so that documents
+// are embedded when hybrid retrieval is enabled.
+func loadDocuments(ctx context.Context, svc *search.Service, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var doc search.Document
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			return fmt.Errorf("decode document: %w", err)
+		}
+		if err := svc.IndexDocument(ctx, &doc); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// evaluate 以指定排序方案跑完所有标注查询，按 URL 匹配标注
+func evaluate(ctx context.Context, svc *search.Service, j releval.Judgments, profile string, k int) (*releval.Report, error) {
+	retrieve := func(ctx context.Context, query string) ([]string, error) {
+		result, err := svc.Retrieve(ctx, query, 1, k, search.SearchOptions{Profile: profile})
+		if err != nil {
+			return nil, err
+		}
+		urls := make([]string, len(result.Hits))
+		for i, hit := range result.Hits {
+			urls[i] = hit.URL
+		}
+		return urls, nil
+	}
+	return releval.Run(ctx, retrieve, j, k)
+}
+
+func label(profile string) string {
+	if profile == "" {
+		return ranking.DefaultName
+	}
+	return profile
+}
+
+func printMean(name string, m releval.Metrics) {
+	fmt.Printf("%-16s %8.4f %8.4f %8.4f\n", name, m.NDCG, m.MRR, m.Precision)
+}
+
+// printChanges 列出 NDCG 变化最大的查询
+func printChanges(base, other *releval.Report, top int) {
+	type change struct {
+		query    string
+		from, to float64
+	}
+	var changes []change
+	for q, m := range base.PerQuery {
+		if d := other.PerQuery[q].NDCG - m.NDCG; math.Abs(d) > 1e-9 {
+			changes = append(changes, change{q, m.NDCG, other.PerQuery[q].NDCG})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		di, dj := math.Abs(changes[i].to-changes[i].from), math.Abs(changes[j].to-changes[j].from)
+		if di != dj {
+			return di > dj
+		}
+		return changes[i].query < changes[j].query
+	})
+	if len(changes) > top {
+		changes = changes[:top]
+	}
+
+	fmt.Printf("\nchanged queries (ndcg):\n")
+	if len(changes) == 0 {
+		fmt.Println("  none")
+	}
+	for _, c := range changes {
+		fmt.Printf("  %+.4f  %.4f -> %.4f  %s\n", c.to-c.from, c.from, c.to, c.query)
+	}
+}
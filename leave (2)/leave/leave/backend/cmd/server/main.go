@@ -1,13 +1,23 @@
 package main
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"search-engine-backend/internal/api"
+	"search-engine-backend/internal/audit"
+	"search-engine-backend/internal/cache"
 	"search-engine-backend/internal/config"
+	"search-engine-backend/internal/curation"
 	"search-engine-backend/internal/filter"
 	"search-engine-backend/internal/ip"
+	"search-engine-backend/internal/querylog"
+	"search-engine-backend/internal/queryrule"
+	"search-engine-backend/internal/ranking"
+	"search-engine-backend/internal/related"
 	"search-engine-backend/internal/search"
+	"search-engine-backend/internal/storage"
 	_ "search-engine-backend/docs" // For Swagger
 )
 
@@ -20,19 +30,136 @@ import (
 func main() {
 	cfg := config.Load()
 
-	svc, err := search.NewService(cfg)
+	cacheSvc := cache.NewCacheService(cfg.RedisAddr, cfg.RedisPassword, cache.Options{
+		Namespace: cfg.CacheNamespace,
+		LocalSize: cfg.LocalCacheSize,
+		LocalTTL:  cfg.LocalCacheTTL,
+	})
+	defer cacheSvc.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 加载排序方案，并监听配置文件变更
+	profiles, err := ranking.NewStore(cfg.RankingProfilesPath)
+	if err != nil {
+		log.Fatalf("Failed to load ranking profiles: %v", err)
+	}
+	profiles.Watch(ctx, cfg.ConfigPollInterval)
+
+	svc, err := search.NewService(cfg, cacheSvc, profiles)
 	if err != nil {
 		log.Fatalf("Failed to initialize search service: %v", err)
 	}
-	defer svc.Close()
+	// 配置了向量维度时启用文本与向量的混合检索
+	if cfg.EmbeddingDims > 0 {
+		svc.SetEmbedder(search.NewHashingEmbedder(cfg.EmbeddingDims))
+	}
+
+	// 加载内容分类模型，索引时为文档标注分类
+	if cfg.ClassifierModelPath != "" {
+		classifier, err := filter.LoadClassifier(cfg.ClassifierModelPath)
+		if err != nil {
+			log.Fatalf("Failed to load classifier: %v", err)
+		}
+		thresholds, err := filter.ParseThresholds(cfg.ClassifierThresholds)
+		if err != nil {
+			log.Fatalf("Invalid classifier thresholds: %v", err)
+		}
+		classifier.SetThresholds(cfg.ClassifierThreshold, thresholds)
+		svc.SetClassifier(classifier)
+	}
 
-	// 初始化 IP 识别服务
-	ipSvc := ip.NewService()
+	// 初始化 IP 识别服务，IP 库文件更新后自动重新加载
+	ipSvc, err := ip.NewService(cfg.GeoIPDatabasePath)
+	if err != nil {
+		log.Fatalf("Failed to open IP database: %v", err)
+	}
+	defer ipSvc.Close()
+	ipSvc.Watch(ctx, cfg.ConfigPollInterval)
 
 	// 初始化内容过滤服务
-	filterSvc := filter.NewService()
+	filterSvc, err := filter.NewService(cfg.FilterKeywordsPath)
+	if err != nil {
+		log.Fatalf("Failed to load filter keywords: %v", err)
+	}
+	for _, d := range cfg.FilterAllowedDomains {
+		if err := filterSvc.AllowDomain(d); err != nil {
+			log.Fatalf("Invalid allowed domain: %v", err)
+		}
+	}
+	filterSvc.Watch(ctx, cfg.ConfigPollInterval)
+
+	// 按地区决定屏蔽哪些分类，以及向用户展示的说明
+	policies, err := filter.NewPolicyStore(cfg.FilterPoliciesPath)
+	if err != nil {
+		log.Fatalf("Failed to load filter policies: %v", err)
+	}
+	policies.Watch(ctx, cfg.ConfigPollInterval)
+	filterSvc.SetPolicies(policies)
+
+	// 导入 hosts/AdBlock/域名列表，文件更新后自动重新导入
+	blocklistSources, err := filter.ParseBlocklistSources(cfg.FilterBlocklists)
+	if err != nil {
+		log.Fatalf("Invalid blocklist config: %v", err)
+	}
+	blocklists := filter.NewImporter(filterSvc, blocklistSources)
+	blocklists.Run(ctx, cfg.FilterBlocklistRefresh)
+
+	db, err := storage.NewDB(cfg.DatabasePath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+
+	// 各实例必须共用同一个 ANALYTICS_SALT；只有显式指定 ANALYTICS_SALT_PATH 的单实例部署
+	// 才使用首次启动随机生成并保存的密钥
+	switch {
+	case cfg.AnalyticsSalt != "":
+	case cfg.AnalyticsSaltPath != "":
+		cfg.AnalyticsSalt, err = querylog.LoadOrCreateSalt(cfg.AnalyticsSaltPath)
+		if err != nil {
+			log.Fatalf("Failed to load analytics salt: %v", err)
+		}
+	default:
+		log.Fatalf("ANALYTICS_SALT is required and must be the same on every instance (single-instance deployments may set ANALYTICS_SALT_PATH instead)")
+	}
+
+	// 记录每一条被过滤的结果，定期清理超过保留期的记录
+	auditSvc := audit.NewService(db, 1024, cfg.AnalyticsSalt, cfg.FilterAuditRetention)
+	defer auditSvc.Close()
+	auditSvc.Run(ctx, time.Hour)
+	filterSvc.SetAuditor(auditSvc)
+
+	// 加载数据库中的屏蔽规则，定期同步其他实例的修改和过期规则
+	filterRules, err := filter.NewRuleStore(db, filterSvc)
+	if err != nil {
+		log.Fatalf("Failed to load filter rules: %v", err)
+	}
+	filterRules.Watch(ctx, cfg.ConfigPollInterval)
+
+	// 初始化查询日志服务
+	queryLog := querylog.NewService(db, 1024)
+	defer queryLog.Close()
+
+	// 定期从会话与点击中挖掘相关搜索
+	related.NewMiner(cacheSvc, db).Run(ctx, cfg.RelatedMineInterval)
+
+	// 初始化运营置顶/隐藏规则
+	curations, err := curation.NewService(db)
+	if err != nil {
+		log.Fatalf("Failed to load curation rules: %v", err)
+	}
+	curations.Watch(ctx, cfg.ConfigPollInterval)
+	svc.SetCurator(curations)
+
+	// 加载查询规则，并监听配置文件变更
+	queryRules, err := queryrule.NewStore(cfg.QueryRulesPath)
+	if err != nil {
+		log.Fatalf("Failed to load query rules: %v", err)
+	}
+	queryRules.Watch(ctx, cfg.ConfigPollInterval)
 
-	handler := api.NewHandler(svc, ipSvc, filterSvc)
+	handler := api.NewHandler(cfg, svc, ipSvc, filterSvc, filterRules, blocklists, auditSvc, queryLog, curations, queryRules)
 	r := api.SetupRouter(handler)
 
 	log.Printf("Server starting on port %s", cfg.ServerPort)
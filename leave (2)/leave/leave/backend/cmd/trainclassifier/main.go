@@ -0,0 +1,148 @@
+// Command trainclassifier trains the content classifier used at index time
+// from labelled JSON lines and reports its accuracy on a held-out split.
+//
+//	go run ./cmd/trainclassifier -data labelled.jsonl -out classifier.json
+//
+// Each line is {"text": "...", "label": "adult"} or {"title": "...",
+// "content": "...", "label": "safe"}; "safe" marks normal content.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+
+	"search-engine-backend/internal/filter"
+)
+
+// record 一行标注数据
+type record struct {
+	Text    string `json:"text"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Label   string `json:"label"`
+}
+
+func main() {
+	dataPath := flag.String("data", "", "labelled JSON lines")
+	outPath := flag.String("out", "classifier.json", "where to write the model")
+	alpha := flag.Float64("alpha", 1, "additive smoothing")
+	holdout := flag.Float64("holdout", 0.2, "fraction of examples held out for evaluation")
+	seed := flag.Int64("seed", 1, "shuffle seed for the held-out split")
+	flag.Parse()
+
+	if *dataPath == "" {
+		log.Fatal("-data is required")
+	}
+	examples, err := loadExamples(*dataPath)
+	if err != nil {
+		log.Fatalf("Failed to load examples: %v", err)
+	}
+
+	rand.New(rand.NewSource(*seed)).Shuffle(len(examples), func(i, j int) {
+		examples[i], examples[j] = examples[j], examples[i]
+	})
+	n := int(float64(len(examples)) * *holdout)
+	test, train := examples[:n], examples[n:]
+
+	if n > 0 {
+		model, err := filter.TrainClassifier(train, *alpha)
+		if err != nil {
+			log.Fatalf("Failed to train: %v", err)
+		}
+		report(model, test)
+	}
+
+	// 评估之后用全部数据训练最终模型
+	model, err := filter.TrainClassifier(examples, *alpha)
+	if err != nil {
+		log.Fatalf("Failed to train: %v", err)
+	}
+	f, err := os.Create(*outPath)
+	if err != nil {
+		log.Fatalf("Failed to create model: %v", err)
+	}
+	if err := model.Save(f); err != nil {
+		log.Fatalf("Failed to write model: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("Failed to write model: %v", err)
+	}
+	fmt.Printf("trained on %d examples, classes %v, model written to %s\n", len(examples), model.Classes, *outPath)
+}
+
+func loadExamples(path string) ([]filter.Example, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var examples []filter.Example
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		text := r.Text
+		if text == "" {
+			text = r.Title + "\n" + r.Content
+		}
+		examples = append(examples, filter.Example{Text: text, Label: r.Label})
+	}
+	return examples, scanner.Err()
+}
+
+// report 打印留出集上的准确率，以及每个类别的精确率和召回率
+func report(model *filter.Classifier, test []filter.Example) {
+	type stats struct{ tp, fp, fn int }
+	per := make(map[string]*stats)
+	get := func(label string) *stats {
+		if per[label] == nil {
+			per[label] = &stats{}
+		}
+		return per[label]
+	}
+	correct := 0
+	for _, ex := range test {
+		got, _ := model.Predict(ex.Text)
+		if got == ex.Label {
+			correct++
+			get(got).tp++
+			continue
+		}
+		get(got).fp++
+		get(ex.Label).fn++
+	}
+
+	fmt.Printf("held-out accuracy: %.3f (%d/%d)\n", float64(correct)/float64(len(test)), correct, len(test))
+	labels := make([]string, 0, len(per))
+	for label := range per {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	fmt.Printf("%-12s %9s %9s\n", "label", "precision", "recall")
+	for _, label := range labels {
+		s := per[label]
+		fmt.Printf("%-12s %9.3f %9.3f\n", label, ratio(s.tp, s.tp+s.fp), ratio(s.tp, s.tp+s.fn))
+	}
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseTimeRange 解析 from/to 参数 (RFC3339)，默认最近 24 小时
+func parseTimeRange(c *gin.Context) (time.Time, time.Time, bool) {
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		to = t
+	}
+	from := to.Add(-24 * time.Hour)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+func parseLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return limit
+}
+
+// @Summary Top Queries
+// @Description Most frequent queries in a time range
+// @Tags admin
+// @Produce json
+// @Param from query string false "RFC3339 start (default 24h before to)"
+// @Param to query string false "RFC3339 end (default now)"
+// @Param limit query int false "Max rows (max 100)"
+// @Success 200 {array} storage.QueryCount
+// @Failure 400 {object} map[string]string
+// @Router /admin/analytics/top-queries [get]
+func (h *Handler) TopQueries(c *gin.Context) {
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time range"})
+		return
+	}
+	rows, err := h.queryLog.TopQueries(from, to, parseLimit(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, rows)
+}
+
+// @Summary Zero Result Queries
+// @Description Most frequent queries without results in a time range
+// @Tags admin
+// @Produce json
+// @Param from query string false "RFC3339 start (default 24h before to)"
+// @Param to query string false "RFC3339 end (default now)"
+// @Param limit query int false "Max rows (max 100)"
+// @Success 200 {array} storage.QueryCount
+// @Failure 400 {object} map[string]string
+// @Router /admin/analytics/zero-results [get]
+func (h *Handler) ZeroResultQueries(c *gin.Context) {
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time range"})
+		return
+	}
+	rows, err := h.queryLog.ZeroResultQueries(from, to, parseLimit(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, rows)
+}
+
+// @Summary Latency Percentiles
+// @Description Search latency percentiles (ms) in a time range
+// @Tags admin
+// @Produce json
+// @Param from query string false "RFC3339 start (default 24h before to)"
+// @Param to query string false "RFC3339 end (default now)"
+// @Success 200 {object} map[string]int64
+// @Failure 400 {object} map[string]string
+// @Router /admin/analytics/latency [get]
+func (h *Handler) LatencyPercentiles(c *gin.Context) {
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time range"})
+		return
+	}
+	percentiles, err := h.queryLog.LatencyPercentiles(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, percentiles)
+}
+
+// @Summary Filter Rate
+// @Description Share of searches with filtered results in a time range
+// @Tags admin
+// @Produce json
+// @Param from query string false "RFC3339 start (default 24h before to)"
+// @Param to query string false "RFC3339 end (default now)"
+// @Success 200 {object} storage.FilterRate
+// @Failure 400 {object} map[string]string
+// @Router /admin/analytics/filter-rate [get]
+func (h *Handler) FilterRate(c *gin.Context) {
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time range"})
+		return
+	}
+	rate, err := h.queryLog.FilterRate(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, rate)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"search-engine-backend/internal/storage"
+)
+
+// FilterAuditResponse 审计记录分页结果
+type FilterAuditResponse struct {
+	Total   int64                 `json:"total"`
+	Records []storage.FilterAudit `json:"records"`
+}
+
+// @Summary Filter Audit Trail
+// @Description Hidden search results with the rule that hid them, newest first
+// @Tags admin
+// @Produce json
+// @Param from query string false "RFC3339 start (default 24h before to)"
+// @Param to query string false "RFC3339 end (default now)"
+// @Param q query string false "Query (matched by its hash)"
+// @Param doc_id query string false "Document ID"
+// @Param rule query string false "Rule, e.g. domain:adult.com or rule:12"
+// @Param category query string false "Category"
+// @Param region query string false "Region"
+// @Param limit query int false "Max rows (max 100)"
+// @Param offset query int false "Rows to skip"
+// @Success 200 {object} FilterAuditResponse
+// @Failure 400 {object} map[string]string
+// @Router /admin/filter/audit [get]
+func (h *Handler) FilterAudit(c *gin.Context) {
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time range"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+	records, total, err := h.audit.Search(storage.AuditQuery{
+		From:     from,
+		To:       to,
+		DocID:    c.Query("doc_id"),
+		Rule:     c.Query("rule"),
+		Category: c.Query("category"),
+		Region:   c.Query("region"),
+		Limit:    parseLimit(c),
+		Offset:   offset,
+	}, c.Query("q"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, FilterAuditResponse{Total: total, Records: records})
+}
+
+// @Summary Filter Audit By Rule
+// @Description Number of hidden results and distinct documents per rule
+// @Tags admin
+// @Produce json
+// @Param from query string false "RFC3339 start (default 24h before to)"
+// @Param to query string false "RFC3339 end (default now)"
+// @Param limit query int false "Max rows (max 100)"
+// @Success 200 {array} storage.RuleCount
+// @Failure 400 {object} map[string]string
+// @Router /admin/filter/audit/rules [get]
+func (h *Handler) FilterAuditByRule(c *gin.Context) {
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time range"})
+		return
+	}
+	rows, err := h.audit.ByRule(from, to, parseLimit(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, rows)
+}
+
+// @Summary Filter Audit Stats
+// @Description Audit write queue length and the number of records dropped because the queue was full
+// @Tags admin
+// @Produce json
+// @Success 200 {object} audit.Stats
+// @Router /admin/filter/audit/stats [get]
+func (h *Handler) FilterAuditStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.audit.Stats())
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// isAdmin 校验 Authorization: Bearer <ADMIN_TOKEN>；未配置令牌时任何请求都不是管理员
+func (h *Handler) isAdmin(c *gin.Context) bool {
+	if h.cfg.AdminToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.cfg.AdminToken)) == 1
+}
+
+// RequireAdmin rejects requests without a valid admin token.
+func (h *Handler) RequireAdmin(c *gin.Context) {
+	if !h.isAdmin(c) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "admin token required"})
+		return
+	}
+	c.Next()
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"search-engine-backend/internal/curation"
+	"search-engine-backend/internal/storage"
+)
+
+// CurationRequest 创建或更新置顶/隐藏规则
+type CurationRequest struct {
+	Pattern string        `json:"pattern" binding:"required"`
+	Match   string        `json:"match"` // exact (默认)、prefix 或 contains
+	Pins    []storage.Pin `json:"pins"`
+	Hidden  []string      `json:"hidden"`
+	Note    string        `json:"note"`
+}
+
+func (r CurationRequest) rule() *storage.CurationRule {
+	return &storage.CurationRule{
+		Pattern: r.Pattern,
+		Match:   r.Match,
+		Pins:    r.Pins,
+		Hidden:  r.Hidden,
+		Note:    r.Note,
+	}
+}
+
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// curationError maps curation errors to HTTP responses.
+func curationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, curation.ErrInvalidRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, storage.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "curation rule not found"})
+	default:
+		log.Printf("curation request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
+
+// @Summary List Curations
+// @Description Pinned and hidden result rules
+// @Tags admin
+// @Produce json
+// @Success 200 {array} storage.CurationRule
+// @Router /admin/curations [get]
+func (h *Handler) ListCurations(c *gin.Context) {
+	c.JSON(http.StatusOK, h.curations.List())
+}
+
+// @Summary Get Curation
+// @Tags admin
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {object} storage.CurationRule
+// @Failure 404 {object} map[string]string
+// @Router /admin/curations/{id} [get]
+func (h *Handler) GetCuration(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	rule, err := h.curations.Get(id)
+	if err != nil {
+		curationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// @Summary Create Curation
+// @Description Pin documents to fixed positions or hide them for matching queries
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param rule body CurationRequest true "Rule"
+// @Success 201 {object} storage.CurationRule
+// @Failure 400 {object} map[string]string
+// @Router /admin/curations [post]
+func (h *Handler) CreateCuration(c *gin.Context) {
+	var req CurationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	rule := req.rule()
+	if err := h.curations.Create(rule); err != nil {
+		curationError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+// @Summary Update Curation
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Param rule body CurationRequest true "Rule"
+// @Success 200 {object} storage.CurationRule
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/curations/{id} [put]
+func (h *Handler) UpdateCuration(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req CurationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	rule := req.rule()
+	rule.ID = id
+	if err := h.curations.Update(rule); err != nil {
+		curationError(c, err)
+		return
+	}
+	updated, err := h.curations.Get(id)
+	if err != nil {
+		curationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// @Summary Delete Curation
+// @Tags admin
+// @Param id path int true "Rule ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /admin/curations/{id} [delete]
+func (h *Handler) DeleteCuration(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.curations.Delete(id); err != nil {
+		curationError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"search-engine-backend/internal/querylog"
+)
+
+// anonCookie 匿名用户 ID，用于实验分组与日志。cookie 值为 "<ID>.<签名>"，
+// 客户端无法自选 ID 来挑选实验组
+const anonCookie = "leave_anon"
+
+// anonymousID returns the visitor's anonymous ID from the cookie. Clients
+// without a validly signed cookie get an ID derived from their IP, so
+// assignment stays stable even if they never store it.
+func (h *Handler) anonymousID(c *gin.Context) string {
+	if value, err := c.Cookie(anonCookie); err == nil {
+		if id, ok := verifyAnonymousID(h.cfg.AnalyticsSalt, value); ok {
+			return id
+		}
+	}
+	id := querylog.AnonymizeClient(h.cfg.AnalyticsSalt, c.ClientIP())
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(anonCookie, signAnonymousID(h.cfg.AnalyticsSalt, id), 365*24*3600, "/", "", false, true)
+	return id
+}
+
+// signAnonymousID returns the cookie value for id.
+func signAnonymousID(salt, id string) string {
+	mac := hmac.New(sha256.New, []byte("anon-cookie:"+salt))
+	mac.Write([]byte(id))
+	return id + "." + hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// verifyAnonymousID returns the ID in a cookie value if its signature is
+// valid.
+func verifyAnonymousID(salt, value string) (string, bool) {
+	id, _, ok := strings.Cut(value, ".")
+	if !ok || len(id) != 16 {
+		return "", false
+	}
+	if !hmac.Equal([]byte(value), []byte(signAnonymousID(salt, id))) {
+		return "", false
+	}
+	return id, true
+}
+
+// ClickRequest 点击上报
+type ClickRequest struct {
+	SearchID string `json:"search_id" binding:"required"`
+	DocID    string `json:"doc_id" binding:"required"`
+	URL      string `json:"url"`
+	Position int    `json:"position"`
+}
+
+// @Summary Click
+// @Description Record a click on a search result
+// @Tags search
+// @Accept json
+// @Produce json
+// @Param click body ClickRequest true "Click"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Router /click [post]
+func (h *Handler) Click(c *gin.Context) {
+	var req ClickRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	anonID := h.anonymousID(c)
+	click := querylog.Click{
+		SearchID: req.SearchID,
+		DocID:    req.DocID,
+		URL:      req.URL,
+		Position: req.Position,
+		ClientID: anonID,
+	}
+	if a, ok := h.svc.Assign(anonID); ok {
+		click.Experiment = a.Experiment
+		click.Arm = a.Arm
+	}
+	h.queryLog.RecordClick(click)
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Experiment Report
+// @Description CTR and zero-result rate per arm of an experiment
+// @Tags admin
+// @Produce json
+// @Param name path string true "Experiment name"
+// @Param from query string false "RFC3339 start (default 24h before to)"
+// @Param to query string false "RFC3339 end (default now)"
+// @Success 200 {array} storage.ArmReport
+// @Failure 400 {object} map[string]string
+// @Router /admin/experiments/{name}/report [get]
+func (h *Handler) ExperimentReport(c *gin.Context) {
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time range"})
+		return
+	}
+	arms, err := h.queryLog.ExperimentReport(c.Param("name"), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, arms)
+}
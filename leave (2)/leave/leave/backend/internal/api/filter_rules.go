@@ -0,0 +1,222 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"search-engine-backend/internal/filter"
+	"search-engine-backend/internal/search"
+	"search-engine-backend/internal/storage"
+)
+
+// FilterRuleRequest 创建或更新屏蔽规则
+type FilterRuleRequest struct {
+	Kind     string `json:"kind" binding:"required"` // domain、keyword、allow、url_regex、wildcard 或 near
+	Value    string `json:"value" binding:"required"`
+	Category string `json:"category"`
+	// Regions 生效地区 (如 CN)，为空时在所有受限地区生效
+	Regions []string `json:"regions"`
+	// Fields keyword 与 near 规则匹配的字段：title、content、url
+	Fields    []string   `json:"fields"`
+	Creator   string     `json:"creator"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (r FilterRuleRequest) rule() *storage.FilterRule {
+	return &storage.FilterRule{
+		Kind:      r.Kind,
+		Value:     r.Value,
+		Category:  r.Category,
+		Regions:   r.Regions,
+		Fields:    r.Fields,
+		Creator:   r.Creator,
+		Reason:    r.Reason,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
+
+// filterRuleError maps filter rule errors to HTTP responses.
+func filterRuleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, filter.ErrInvalidRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, storage.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "filter rule not found"})
+	default:
+		log.Printf("filter rule request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
+
+// @Summary List Filter Rules
+// @Description Domain, keyword, allow, URL regex, wildcard and near rules, including expired ones
+// @Tags admin
+// @Produce json
+// @Success 200 {array} storage.FilterRule
+// @Router /admin/filter/rules [get]
+func (h *Handler) ListFilterRules(c *gin.Context) {
+	rules, err := h.filterRules.List()
+	if err != nil {
+		filterRuleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// @Summary Get Filter Rule
+// @Tags admin
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {object} storage.FilterRule
+// @Failure 404 {object} map[string]string
+// @Router /admin/filter/rules/{id} [get]
+func (h *Handler) GetFilterRule(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	rule, err := h.filterRules.Get(id)
+	if err != nil {
+		filterRuleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// @Summary Create Filter Rule
+// @Description Takes effect immediately on this instance and within the poll interval on others
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param rule body FilterRuleRequest true "Rule"
+// @Success 201 {object} storage.FilterRule
+// @Failure 400 {object} map[string]string
+// @Router /admin/filter/rules [post]
+func (h *Handler) CreateFilterRule(c *gin.Context) {
+	var req FilterRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	rule := req.rule()
+	if err := h.filterRules.Create(rule); err != nil {
+		filterRuleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+// @Summary Update Filter Rule
+// @Description Replaces the rule; the creator is kept
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Param rule body FilterRuleRequest true "Rule"
+// @Success 200 {object} storage.FilterRule
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/filter/rules/{id} [put]
+func (h *Handler) UpdateFilterRule(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req FilterRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	rule := req.rule()
+	rule.ID = id
+	if err := h.filterRules.Update(rule); err != nil {
+		filterRuleError(c, err)
+		return
+	}
+	updated, err := h.filterRules.Get(id)
+	if err != nil {
+		filterRuleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// @Summary Delete Filter Rule
+// @Tags admin
+// @Param id path int true "Rule ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /admin/filter/rules/{id} [delete]
+func (h *Handler) DeleteFilterRule(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.filterRules.Delete(id); err != nil {
+		filterRuleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// DryRunRequest 规则试运行：用一个文档检验现有规则和待添加的规则
+type DryRunRequest struct {
+	Document search.Document `json:"document"`
+	// Region 与 Safe 模拟请求的地区和安全搜索级别，决定规则是否生效
+	Region string `json:"region"`
+	Safe   string `json:"safe"`
+	// Rules 尚未保存的规则，只参与匹配，不影响实际判定
+	Rules []FilterRuleRequest `json:"rules"`
+}
+
+// @Summary Dry Run Filter Rules
+// @Description Which stored and candidate rules match a document, whether each is enforced in the region, and the filter's actual decision. Nothing is saved or audited
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body DryRunRequest true "Document, region and candidate rules"
+// @Success 200 {object} filter.DryRunResult
+// @Failure 400 {object} map[string]string
+// @Router /admin/filter/dry-run [post]
+func (h *Handler) DryRunFilterRules(c *gin.Context) {
+	var req DryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	safe := filter.SafeOff
+	if req.Safe != "" {
+		level, ok := filter.ParseSafeSearch(req.Safe)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid safe parameter"})
+			return
+		}
+		safe = level
+	}
+	candidates := make([]storage.FilterRule, 0, len(req.Rules))
+	for _, r := range req.Rules {
+		candidates = append(candidates, *r.rule())
+	}
+	filterReq := filter.Request{Region: strings.ToUpper(strings.TrimSpace(req.Region)), SafeSearch: safe}
+	result, err := h.filterRules.DryRun(req.Document, filterReq, candidates)
+	if err != nil {
+		filterRuleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Blocklist Imports
+// @Description Latest import result of each hosts, AdBlock or domain list file
+// @Tags admin
+// @Produce json
+// @Success 200 {array} filter.ImportResult
+// @Router /admin/filter/blocklists [get]
+func (h *Handler) Blocklists(c *gin.Context) {
+	c.JSON(http.StatusOK, h.blocklists.Results())
+}
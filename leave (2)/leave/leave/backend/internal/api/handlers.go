@@ -1,28 +1,55 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"html"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"search-engine-backend/internal/audit"
+	"search-engine-backend/internal/cache"
+	"search-engine-backend/internal/config"
+	"search-engine-backend/internal/curation"
 	"search-engine-backend/internal/filter"
 	"search-engine-backend/internal/ip"
+	"search-engine-backend/internal/querylog"
+	"search-engine-backend/internal/queryrule"
+	"search-engine-backend/internal/ranking"
 	"search-engine-backend/internal/search"
 )
 
 type Handler struct {
-	svc    *search.Service
-	ipSvc  *ip.Service
-	filter *filter.Service
+	cfg         *config.Config
+	svc         *search.Service
+	ipSvc       *ip.Service
+	filter      *filter.Service
+	filterRules *filter.RuleStore
+	blocklists  *filter.Importer
+	audit       *audit.Service
+	queryLog    *querylog.Service
+	curations   *curation.Service
+	queryRules  *queryrule.Store
+	cursors     *refillCursors
 }
 
-func NewHandler(svc *search.Service, ipSvc *ip.Service, filter *filter.Service) *Handler {
+func NewHandler(cfg *config.Config, svc *search.Service, ipSvc *ip.Service, filter *filter.Service, filterRules *filter.RuleStore, blocklists *filter.Importer, audit *audit.Service, queryLog *querylog.Service, curations *curation.Service, queryRules *queryrule.Store) *Handler {
 	return &Handler{
-		svc:    svc,
-		ipSvc:  ipSvc,
-		filter: filter,
+		cfg:         cfg,
+		svc:         svc,
+		ipSvc:       ipSvc,
+		filter:      filter,
+		filterRules: filterRules,
+		blocklists:  blocklists,
+		audit:       audit,
+		queryLog:    queryLog,
+		curations:   curations,
+		queryRules:  queryRules,
+		cursors:     newRefillCursors(),
 	}
 }
 
@@ -66,8 +93,24 @@ func validatePagination(pageStr, sizeStr string) (int, int) {
 // SearchResponse 扩展原有的 SearchResult，增加过滤信息
 type SearchResponse struct {
 	*search.SearchResult
+	// SearchID 用于把点击关联到本次搜索
+	SearchID string `json:"search_id"`
 	Filtered bool   `json:"filtered"`
 	Message  string `json:"message,omitempty"`
+	// LegalReference 过滤依据的法规，来自当前地区的过滤策略
+	LegalReference string `json:"legal_reference,omitempty"`
+	// RewrittenQuery 查询规则改写后实际搜索的查询词
+	RewrittenQuery string `json:"rewritten_query,omitempty"`
+	// Redirect 导航类查询直接跳转的地址，此时不返回搜索结果
+	Redirect string `json:"redirect,omitempty"`
+	// Refused 查询被当前地区的政策规则拒绝，Message 为说明
+	Refused bool `json:"refused,omitempty"`
+	// SafeSearch 实际生效的安全搜索级别，不低于当前地区策略的下限
+	SafeSearch string `json:"safe_search"`
+	// SafeSearchFiltered 因安全搜索 (而非地区策略) 隐藏的结果数
+	SafeSearchFiltered int `json:"safe_search_filtered,omitempty"`
+	// TotalEstimated 表示部分结果被过滤，Total 为估算值
+	TotalEstimated bool `json:"total_estimated,omitempty"`
 }
 
 // @Summary Search
@@ -78,6 +121,8 @@ type SearchResponse struct {
 // @Param q query string true "Query string (max 100 chars)"
 // @Param page query int false "Page number (min 1)"
 // @Param size query int false "Page size (max 50)"
+// @Param profile query string false "Ranking profile (admin only)"
+// @Param safe query string false "SafeSearch level: off, moderate or strict (remembered in a cookie)"
 // @Success 200 {object} search.SearchResult
 // @Failure 400 {object} map[string]string
 // @Router /search [get]
@@ -91,32 +136,138 @@ func (h *Handler) Search(c *gin.Context) {
 
 	page, size := validatePagination(c.DefaultQuery("page", "1"), c.DefaultQuery("size", "10"))
 
-	result, err := h.svc.Search(c.Request.Context(), query, page, size)
+	// 排序方案仅对管理员开放，普通用户的 profile 参数被忽略；
+	// 未指定方案时按匿名 ID 分配实验组
+	anonID := h.anonymousID(c)
+	var opts search.SearchOptions
+	if h.isAdmin(c) {
+		opts.Profile = c.Query("profile")
+	}
+	assignment, inExperiment := h.svc.Assign(anonID)
+	if opts.Profile == "" && inExperiment {
+		opts.Profile = assignment.Profile
+		opts.Variant = assignment.Variant
+	} else {
+		assignment = ranking.Assignment{}
+	}
+
+	// 查询规则在调用 ES 之前执行：改写、导航直达或按地区拒绝
+	clientIP := c.ClientIP()
+	region := h.ipSvc.Region(clientIP)
+	policy := h.filter.Policy(region)
+	safe := h.filter.SafeSearch(region, h.safeSearch(c))
+	decision := h.queryRules.Apply(query, region)
+	switch decision.Action {
+	case queryrule.ActionRedirect:
+		c.JSON(http.StatusOK, SearchResponse{
+			SearchResult: &search.SearchResult{Hits: []search.Document{}},
+			Redirect:     decision.URL,
+		})
+		return
+	case queryrule.ActionRefuse:
+		c.JSON(http.StatusOK, SearchResponse{
+			SearchResult: &search.SearchResult{Hits: []search.Document{}},
+			Refused:      true,
+			Message:      decision.Message,
+		})
+		return
+	}
+
+	// 会话序列用于挖掘相关搜索，只记录首页；输入联想请求 (size=0，首页输入框会发送)
+	// 与当前地区屏蔽的查询词不计入。validatePagination 会把 size=0 改为默认值，因此检查原始参数
+	if page == 1 && c.Query("size") != "0" && !h.filter.IsQueryBlocked(query, region, filter.SafeOff) {
+		opts.Session = anonID
+	}
+
+	// 过滤规则按地区策略生效，安全搜索在其基础上屏蔽更多分类。
+	// 过滤在 ES 查询之后进行，因此多取结果，用后续命中补齐被隐藏的条目
+	// 审计按原始查询词的哈希检索，与管理员输入的查询词一致，因此还原转义
+	filterReq := filter.Request{Query: html.UnescapeString(query), Region: region, SafeSearch: filter.SafeOff}
+	var keep hitFilter = func(hits []search.Document, audit bool) ([]search.Document, int, int) {
+		// 先按地区策略过滤，再按用户的安全搜索级别过滤，以便分别提示
+		req := filterReq
+		req.NoAudit = !audit
+		kept, regional := h.filter.Filter(hits, req)
+		if safe == filter.SafeOff {
+			return kept, regional, 0
+		}
+		req.SafeSearch = safe
+		kept, safeCount := h.filter.Filter(kept, req)
+		return kept, regional, safeCount
+	}
+	fetch := func(page, size int) (*search.SearchResult, error) {
+		return h.svc.Search(c.Request.Context(), decision.Query, page, size, opts)
+	}
+	// 已知的分页起点按查询、排序方案、地区和安全搜索级别缓存，结果或过滤规则变化后失效
+	cursorKey := fmt.Sprintf("%d|%s|%s|%s|%s|%s|%d|%s", h.svc.ResultsGeneration(c.Request.Context()), h.filter.Version(),
+		opts.Profile, opts.Variant, region, safe, size, decision.Query)
+	if !h.filter.Active(region, safe) {
+		keep = nil
+	}
+
+	start := time.Now()
+	starts := h.cursors.load(cursorKey)
+	refilled, err := refillPage(page, size, starts, fetch, keep)
+	latency := time.Since(start)
 	if err != nil {
+		if errors.Is(err, ranking.ErrUnknownProfile) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown ranking profile"})
+			return
+		}
 		// 避免将内部错误细节暴露给客户端
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 		return
 	}
+	h.cursors.store(cursorKey, starts)
+	result := refilled.SearchResult
+	filteredCount := refilled.Regional
 
-	// IP 识别与内容过滤
-	clientIP := c.ClientIP()
-	isCN := h.ipSvc.IsChinaMainland(clientIP)
-	
+	// 内容过滤
 	var response SearchResponse
 	response.SearchResult = result
+	response.SearchID = querylog.NewSearchID()
+	response.TotalEstimated = refilled.Estimated
+	if decision.Rewritten {
+		response.RewrittenQuery = decision.Query
+	}
+
+	// 相关搜索中同样去掉敏感查询词
+	response.SafeSearch = safe
+	related := make([]string, 0, len(result.Suggestions))
+	for _, q := range result.Suggestions {
+		if !h.filter.IsQueryBlocked(q, region, safe) {
+			related = append(related, q)
+		}
+	}
+	response.Suggestions = related
+
+	response.SafeSearchFiltered = refilled.SafeSearch
+	response.Filtered = filteredCount > 0 || refilled.SafeSearch > 0
+	if filteredCount > 0 && policy != nil {
+		response.Message = policy.Notice
+		response.LegalReference = policy.LegalReference
+	}
 
-	if isCN {
-		filteredHits, count := h.filter.Filter(result.Hits)
-		if count > 0 {
-			response.Hits = filteredHits
-			response.Filtered = true
-			response.Message = "根据相关法律法规和政策，部分搜索结果未予显示。"
-			// 修正 Total 数量，减去被过滤的条数 (虽然这只是当前页的过滤，但给用户一个反馈)
-			// 注意：实际上如果只过滤当前页，分页可能会乱。理想做法是在 ES 查询时就加上过滤条件。
-			// 但基于目前的需求“对搜索结果中的成人内容进行实时屏蔽处理”，这种后处理方式是可接受的中间件模式。
+	// 记录热搜：只统计首页请求，降级结果和当前地区屏蔽的查询词不计入
+	if page == 1 && !result.Degraded && !h.filter.IsQueryBlocked(query, region, filter.SafeOff) {
+		if err := h.svc.RecordQuery(c.Request.Context(), query); err != nil {
+			log.Printf("failed to record hot query: %v", err)
 		}
 	}
 
+	h.queryLog.Record(querylog.Entry{
+		SearchID:      response.SearchID,
+		Query:         search.NormalizeQuery(query),
+		ResultCount:   result.Total,
+		Latency:       latency,
+		Page:          page,
+		Restricted:    policy != nil,
+		FilteredCount: filteredCount,
+		ClientID:      anonID,
+		Experiment:    assignment.Experiment,
+		Arm:           assignment.Arm,
+	})
+
 	c.JSON(http.StatusOK, response)
 }
 
@@ -138,6 +289,10 @@ func (h *Handler) Index(c *gin.Context) {
 	// 简单的输入清理
 	doc.Title = html.EscapeString(doc.Title)
 	doc.Content = html.EscapeString(doc.Content)
+	// 内容分类只由索引时的分类器判定，不信任客户端提交的值
+	doc.Category = ""
+	doc.CategoryConfidence = 0
+	doc.Classified = false
 
 	if err := h.svc.IndexDocument(c.Request.Context(), &doc); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "indexing failed"})
@@ -147,6 +302,109 @@ func (h *Handler) Index(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "indexed"})
 }
 
+// trendingOverFetch 热搜按地区过滤前多取的倍数
+const trendingOverFetch = 3
+
+// @Summary Trending
+// @Description Top queries of a time window with decay
+// @Tags search
+// @Produce json
+// @Param window query string false "hour or day" default(hour)
+// @Param limit query int false "Number of queries (max 50)"
+// @Param safe query string false "SafeSearch level: off, moderate or strict"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /trending [get]
+func (h *Handler) Trending(c *gin.Context) {
+	window := c.DefaultQuery("window", "hour")
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	// 热搜词来自所有地区，按请求方的地区和安全搜索级别过滤；多取一些以便过滤后仍能填满
+	queries, err := h.svc.Trending(c.Request.Context(), window, int64(limit*trendingOverFetch))
+	if err != nil {
+		if errors.Is(err, cache.ErrUnknownWindow) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid window parameter"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	region := h.ipSvc.Region(c.ClientIP())
+	safe := h.filter.SafeSearch(region, h.safeSearch(c))
+	visible := make([]cache.HotQuery, 0, limit)
+	for _, q := range queries {
+		if len(visible) == limit {
+			break
+		}
+		if !h.filter.IsQueryBlocked(q.Query, region, safe) {
+			visible = append(visible, q)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"window": window, "queries": visible})
+}
+
+// @Summary Ranking Profiles
+// @Description Names of the configured ranking profiles
+// @Tags admin
+// @Produce json
+// @Success 200 {array} string
+// @Router /admin/ranking/profiles [get]
+func (h *Handler) RankingProfiles(c *gin.Context) {
+	c.JSON(http.StatusOK, h.svc.Profiles())
+}
+
+// @Summary Explain Ranking
+// @Description Query AST, analyzer tokens, ES query and the ES _explain breakdown for one document
+// @Tags admin
+// @Produce json
+// @Param q query string true "Search query"
+// @Param id query string true "Document ID"
+// @Param profile query string false "Ranking profile"
+// @Success 200 {object} search.Explanation
+// @Router /admin/explain [get]
+func (h *Handler) Explain(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	docID := strings.TrimSpace(c.Query("id"))
+	if query == "" || docID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q and id are required"})
+		return
+	}
+
+	ex, err := h.svc.Explain(c.Request.Context(), query, docID, search.SearchOptions{Profile: c.Query("profile")})
+	if err != nil {
+		switch {
+		case errors.Is(err, ranking.ErrUnknownProfile):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown ranking profile"})
+		case errors.Is(err, search.ErrDocumentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		case errors.Is(err, search.ErrExplainUnsupported):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "explain is not supported by the search engine"})
+		default:
+			log.Printf("explain failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, ex)
+}
+
+// @Summary Cache Stats
+// @Description Cache hit/miss and error counters
+// @Tags admin
+// @Produce json
+// @Success 200 {object} cache.Stats
+// @Router /admin/cache/stats [get]
+func (h *Handler) CacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.svc.CacheStats())
+}
+
 func (h *Handler) Health(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
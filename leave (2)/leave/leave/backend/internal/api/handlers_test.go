@@ -1,9 +1,23 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"search-engine-backend/internal/cache"
+	"search-engine-backend/internal/config"
+	"search-engine-backend/internal/filter"
+	"search-engine-backend/internal/ip"
+	"search-engine-backend/internal/ranking"
+	"search-engine-backend/internal/search"
 )
 
 func TestValidateSearchInput(t *testing.T) {
@@ -56,3 +70,144 @@ func TestValidatePagination(t *testing.T) {
 		})
 	}
 }
+
+func TestSafeSearchCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &Handler{cfg: &config.Config{SafeSearchDefault: "moderate"}}
+	level := func(target string, cookie *http.Cookie) (string, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+		if cookie != nil {
+			c.Request.AddCookie(cookie)
+		}
+		return h.safeSearch(c), w
+	}
+
+	got, w := level("/api/search?q=x", nil)
+	assert.Equal(t, filter.SafeModerate, got)
+	assert.Empty(t, w.Result().Cookies())
+
+	got, w = level("/api/search?q=x&safe=OFF", nil)
+	assert.Equal(t, filter.SafeOff, got)
+	cookies := w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, safeSearchCookie, cookies[0].Name)
+		got, _ = level("/api/search?q=x", cookies[0])
+		assert.Equal(t, filter.SafeOff, got, "remembered in the cookie")
+	}
+
+	got, _ = level("/api/search?q=x&safe=bogus", &http.Cookie{Name: safeSearchCookie, Value: "strict"})
+	assert.Equal(t, filter.SafeStrict, got)
+}
+
+func TestAnonymousIDCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &Handler{cfg: &config.Config{AnalyticsSalt: "salt"}}
+	anonID := func(remoteAddr string, cookie *http.Cookie) (string, []*http.Cookie) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/search?q=x", nil)
+		c.Request.RemoteAddr = remoteAddr
+		if cookie != nil {
+			c.Request.AddCookie(cookie)
+		}
+		return h.anonymousID(c), w.Result().Cookies()
+	}
+
+	id, cookies := anonID("192.0.2.1:1234", nil)
+	assert.Len(t, id, 16)
+	require.Len(t, cookies, 1)
+	assert.NotEqual(t, id, cookies[0].Value, "the cookie is signed")
+
+	// 换了 IP 仍沿用 cookie 中的 ID
+	got, reissued := anonID("192.0.2.2:1234", cookies[0])
+	assert.Equal(t, id, got)
+	assert.Empty(t, reissued)
+
+	// 自选或篡改的 ID 被忽略，按 IP 重新分配
+	for _, value := range []string{"0123456789abcdef", "0123456789abcdef" + cookies[0].Value[16:], cookies[0].Value + "0"} {
+		got, reissued = anonID("192.0.2.2:1234", &http.Cookie{Name: anonCookie, Value: value})
+		assert.NotEqual(t, "0123456789abcdef", got, value)
+		assert.NotEqual(t, id, got, value)
+		assert.Len(t, reissued, 1, value)
+	}
+
+	// 其他密钥签发的 cookie 无效
+	_, ok := verifyAnonymousID("other", cookies[0].Value)
+	assert.False(t, ok)
+}
+
+func TestTrendingFilteredByRegion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	cacheSvc := cache.NewCacheService(mr.Addr(), "", cache.Options{Namespace: "test:"})
+	defer cacheSvc.Close()
+	profiles, err := ranking.NewStore("")
+	require.NoError(t, err)
+	svc := search.NewServiceWithEngine(&config.Config{}, cacheSvc, profiles, search.NewMemoryEngine())
+	ipSvc, err := ip.NewService("")
+	require.NoError(t, err)
+	filterSvc, err := filter.NewService("")
+	require.NoError(t, err)
+	h := &Handler{cfg: &config.Config{SafeSearchDefault: "off"}, svc: svc, ipSvc: ipSvc, filter: filterSvc}
+
+	ctx := context.Background()
+	// 在不受限地区搜索的敏感词同样进入热搜
+	for i, q := range []string{"porn", "porn", "porn", "golang", "golang", "redis"} {
+		require.NoError(t, svc.RecordQuery(ctx, q), i)
+	}
+	trending := func(remoteAddr string) []string {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/trending?limit=2", nil)
+		c.Request.RemoteAddr = remoteAddr
+		h.Trending(c)
+		require.Equal(t, http.StatusOK, w.Code)
+		var body struct {
+			Queries []cache.HotQuery `json:"queries"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		var queries []string
+		for _, q := range body.Queries {
+			queries = append(queries, q.Query)
+		}
+		return queries
+	}
+
+	assert.Equal(t, []string{"porn", "golang"}, trending("192.0.2.1:1234"))
+	assert.Equal(t, []string{"golang", "redis"}, trending("10.0.0.1:1234"), "filtered in CN and still filled up to limit")
+}
+
+type stubClassifier struct{ category string }
+
+func (c stubClassifier) Classify(title, content string) (string, float64) {
+	return c.category, 0.9
+}
+
+func TestIndexIgnoresClientCategory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	cacheSvc := cache.NewCacheService(mr.Addr(), "", cache.Options{Namespace: "test:"})
+	defer cacheSvc.Close()
+	profiles, err := ranking.NewStore("")
+	require.NoError(t, err)
+	engine := search.NewMemoryEngine()
+	svc := search.NewServiceWithEngine(&config.Config{}, cacheSvc, profiles, engine)
+	svc.SetClassifier(stubClassifier{category: "adult"})
+	h := &Handler{cfg: &config.Config{}, svc: svc}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"id":"1","title":"x","url":"https://example.com","category":"news","category_confidence":1}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/index", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	h.Index(c)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	docs, err := engine.Documents(context.Background(), []string{"1"})
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "adult", docs[0].Category, "client-supplied category must not skip the classifier")
+	assert.Equal(t, 0.9, docs[0].CategoryConfidence)
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	"search-engine-backend/internal/cache"
+	"search-engine-backend/internal/search"
+)
+
+const (
+	// refillBatch 补齐分页时每次向引擎请求的条数，与最大每页数量一致以便复用缓存
+	refillBatch = 50
+	// refillScanLimit 一次请求最多扫描的原始结果数，过滤比例极高时避免无限制地扫描
+	refillScanLimit = 2000
+	// refillCursorTTL 分页起点的缓存时间，与搜索结果缓存一致
+	refillCursorTTL = 5 * time.Minute
+	// refillCursorEntries 进程内最多缓存的查询数，refillCursorPages 每个查询最多保留的分页起点数
+	refillCursorEntries = 10000
+	refillCursorPages   = 100
+)
+
+// pageFetcher returns one raw page of results.
+type pageFetcher func(page, size int) (*search.SearchResult, error)
+
+// hitFilter drops hidden hits and reports how many were removed by the
+// regional policy and by SafeSearch. Hidden hits are recorded in the audit
+// log only when audit is true.
+type hitFilter func(hits []search.Document, audit bool) (kept []search.Document, regional, safe int)
+
+// pageStarts maps a page number to the offset in the raw results right
+// after the last visible hit of the previous page. Page 1 starts at 0.
+type pageStarts map[int]int
+
+// resume returns the latest known page at or before page and its offset.
+func (s pageStarts) resume(page int) (int, int) {
+	from, offset := 1, 0
+	for p, o := range s {
+		if p <= page && p > from {
+			from, offset = p, o
+		}
+	}
+	return from, offset
+}
+
+// refilledPage is one page of visible results.
+type refilledPage struct {
+	*search.SearchResult
+	// Regional 与 SafeSearch 为本页范围内被隐藏的条数：从上一页最后一条可见结果之后
+	// 到本页最后一条可见结果为止
+	Regional   int
+	SafeSearch int
+	// Estimated 表示 Total 是按扫描范围内的过滤比例估算的
+	Estimated bool
+}
+
+// refillPage builds page of size visible hits. Raw results are scanned in
+// fixed batches from the start of the latest page in starts at or before
+// page, so page N always continues exactly where page N-1 stopped, however
+// deep. The start of every page reached is added to starts, so paging
+// forward costs one or two batches. A nil keep means nothing can be
+// hidden, and the raw page is returned as is.
+//
+// Only hits hidden between the last visible hit of the previous page and the
+// last visible hit of this page are audited and counted; the rest belong to
+// other pages.
+func refillPage(page, size int, starts pageStarts, fetch pageFetcher, keep hitFilter) (*refilledPage, error) {
+	if keep == nil {
+		raw, err := fetch(page, size)
+		if err != nil {
+			return nil, err
+		}
+		return &refilledPage{SearchResult: raw}, nil
+	}
+
+	first, base := starts.resume(page)
+	var out *refilledPage
+	// scanned 为从 base 开始扫描到的原始结果，positions[i] 为第 i 条可见结果在其中的下标
+	var scanned, visible []search.Document
+	var positions []int
+	need := (page - first + 1) * size
+	exhausted := false
+	for len(visible) < need && len(scanned) < refillScanLimit {
+		offset := base + len(scanned)
+		batch := offset/refillBatch + 1
+		raw, err := fetch(batch, refillBatch)
+		if err != nil {
+			if out == nil {
+				return nil, err
+			}
+			// 已有部分结果时不让整个请求失败
+			log.Printf("failed to refill search page from batch %d: %v", batch, err)
+			break
+		}
+		if out == nil {
+			result := *raw
+			out = &refilledPage{SearchResult: &result}
+		} else {
+			out.Degraded = out.Degraded || raw.Degraded
+			out.Curated = out.Curated || raw.Curated
+		}
+		hits := raw.Hits
+		if skip := offset % refillBatch; skip < len(hits) {
+			hits = hits[skip:]
+		} else {
+			hits = nil
+		}
+		kept, _, _ := keep(hits, false)
+		positions = appendPositions(positions, len(scanned), hits, kept)
+		scanned = append(scanned, hits...)
+		visible = append(visible, kept...)
+		if len(raw.Hits) < refillBatch || int64(batch*refillBatch) >= raw.Total {
+			exhausted = true
+			break
+		}
+	}
+	for p := 1; p*size <= len(visible); p++ {
+		starts[first+p] = base + positions[p*size-1] + 1
+	}
+
+	// 本页在本次扫描的可见结果中的范围
+	start, end := (page-first)*size, (page-first+1)*size
+	if start < len(visible) || start == 0 {
+		from, to := 0, len(scanned)
+		if start > 0 {
+			from = positions[start-1] + 1
+		}
+		if end <= len(visible) {
+			to = positions[end-1] + 1
+		}
+		_, out.Regional, out.SafeSearch = keep(scanned[from:to], true)
+	}
+	if start > len(visible) {
+		start = len(visible)
+	}
+	if end > len(visible) {
+		end = len(visible)
+	}
+	out.Hits = visible[start:end]
+
+	// 起点之前被隐藏的条数可由起点的位置推算
+	before := (first - 1) * size
+	hidden := base - before + len(scanned) - len(visible)
+	total := before + len(visible)
+	switch {
+	case hidden == 0:
+	case exhausted:
+		out.Total = int64(total)
+	case len(visible) < need:
+		// 扫描上限内填不满本页，不再提供更深的分页
+		out.Total = int64(total)
+		out.Estimated = true
+	default:
+		out.Total = estimateTotal(out.Total, base+len(scanned), total)
+		out.Estimated = true
+	}
+	return out, nil
+}
+
+// appendPositions appends the positions of kept within hits, offset by
+// base. kept is an ordered subsequence of hits.
+func appendPositions(positions []int, base int, hits, kept []search.Document) []int {
+	j := 0
+	for i, doc := range hits {
+		if j < len(kept) && kept[j].ID == doc.ID {
+			positions = append(positions, base+i)
+			j++
+		}
+	}
+	return positions
+}
+
+// estimateTotal scales the raw total by the share of scanned hits that
+// survived filtering.
+func estimateTotal(total int64, scanned, kept int) int64 {
+	if scanned == 0 {
+		return total
+	}
+	estimate := total * int64(kept) / int64(scanned)
+	if estimate < int64(kept) {
+		estimate = int64(kept)
+	}
+	return estimate
+}
+
+// refillCursors 进程内缓存各查询已知的分页起点，向后翻页时无需从头扫描。
+// key 包含搜索结果代数和过滤规则版本，结果或规则变化后不再使用旧的起点
+type refillCursors struct {
+	local *cache.LocalCache
+}
+
+func newRefillCursors() *refillCursors {
+	return &refillCursors{local: cache.NewLocalCache(refillCursorEntries, refillCursorTTL)}
+}
+
+func (r *refillCursors) load(key string) pageStarts {
+	starts := pageStarts{}
+	if data, ok := r.local.Get(key); ok {
+		if err := json.Unmarshal(data, &starts); err != nil {
+			return pageStarts{}
+		}
+	}
+	return starts
+}
+
+func (r *refillCursors) store(key string, starts pageStarts) {
+	if len(starts) == 0 {
+		return
+	}
+	// 只保留最深的若干个起点，防止单个查询无限增长；较浅的分页从头扫描的代价很小
+	if len(starts) > refillCursorPages {
+		pages := make([]int, 0, len(starts))
+		for p := range starts {
+			pages = append(pages, p)
+		}
+		sort.Ints(pages)
+		for _, p := range pages[:len(pages)-refillCursorPages] {
+			delete(starts, p)
+		}
+	}
+	data, err := json.Marshal(starts)
+	if err != nil {
+		return
+	}
+	r.local.Set(key, data)
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"search-engine-backend/internal/search"
+)
+
+func everyThird(id int) bool { return id%3 == 0 }
+
+// rawResults serves n documents "0".."n-1", hiding those selected by hide.
+// Hidden IDs passed to keep with audit set are appended to audited.
+func rawResults(n int, hide func(id int) bool) (pageFetcher, hitFilter, *int, *[]string) {
+	docs := make([]search.Document, n)
+	for i := range docs {
+		docs[i] = search.Document{ID: strconv.Itoa(i)}
+	}
+	calls := 0
+	var audited []string
+	fetch := func(page, size int) (*search.SearchResult, error) {
+		calls++
+		from, to := (page-1)*size, page*size
+		if from > n {
+			from = n
+		}
+		if to > n {
+			to = n
+		}
+		return &search.SearchResult{Total: int64(n), Hits: docs[from:to]}, nil
+	}
+	keep := func(hits []search.Document, audit bool) ([]search.Document, int, int) {
+		var kept []search.Document
+		hidden := 0
+		for _, doc := range hits {
+			if id, _ := strconv.Atoi(doc.ID); hide(id) {
+				hidden++
+				if audit {
+					audited = append(audited, doc.ID)
+				}
+				continue
+			}
+			kept = append(kept, doc)
+		}
+		return kept, hidden, 0
+	}
+	return fetch, keep, &calls, &audited
+}
+
+func ids(hits []search.Document) []string {
+	out := make([]string, len(hits))
+	for i, doc := range hits {
+		out[i] = doc.ID
+	}
+	return out
+}
+
+func TestRefillPage(t *testing.T) {
+	fetch, keep, calls, audited := rawResults(300, everyThird)
+
+	// 相邻页首尾衔接，每页都是满的；从上一页记下的起点继续，不从头扫描
+	starts := pageStarts{}
+	var seen []string
+	for page := 1; page <= 3; page++ {
+		p, err := refillPage(page, 10, starts, fetch, keep)
+		require.NoError(t, err)
+		assert.Len(t, p.Hits, 10)
+		assert.True(t, p.Estimated)
+		assert.Equal(t, int64(198), p.Total, "33 of the first 50 are visible")
+		assert.Equal(t, 5, p.Regional, "only hits hidden within the page are counted")
+		seen = append(seen, ids(p.Hits)...)
+	}
+	assert.Equal(t, []string{"1", "2", "4", "5", "7"}, seen[:5])
+	assert.Equal(t, []string{"43", "44"}, seen[28:])
+	assert.Equal(t, 3, *calls, "one batch per page")
+	assert.Equal(t, 15, starts[2], "page 2 starts right after doc 14")
+	// 每条隐藏结果只在它所在的那一页记录一次
+	assert.Equal(t, []string{"0", "3", "6", "9", "12", "15", "18", "21", "24", "27", "30", "33", "36", "39", "42"}, *audited)
+
+	// 扫描到末尾时 Total 为准确值，最后一页可以不满
+	fetch, keep, _, audited = rawResults(40, everyThird)
+	p, err := refillPage(3, 12, pageStarts{}, fetch, keep)
+	require.NoError(t, err)
+	assert.False(t, p.Estimated)
+	assert.Equal(t, int64(26), p.Total)
+	assert.Len(t, p.Hits, 2)
+	assert.Equal(t, []string{"36", "39"}, *audited, "trailing hidden hits belong to the last page")
+	p, err = refillPage(5, 12, pageStarts{}, fetch, keep)
+	require.NoError(t, err)
+	assert.NotNil(t, p.Hits)
+	assert.Empty(t, p.Hits)
+	assert.Len(t, *audited, 2)
+
+	// 没有可过滤的规则时直接返回原始页
+	p, err = refillPage(2, 10, pageStarts{}, fetch, nil)
+	require.NoError(t, err)
+	assert.False(t, p.Estimated)
+	assert.Equal(t, int64(40), p.Total)
+	assert.Equal(t, []string{"10", "11", "12", "13", "14", "15", "16", "17", "18", "19"}, ids(p.Hits))
+
+	// 直接跳到深分页时从头扫描，不返回空页
+	fetch, keep, _, _ = rawResults(2000, func(id int) bool { return id%4 != 0 })
+	p, err = refillPage(40, 10, pageStarts{}, fetch, keep)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1560", "1564", "1568", "1572", "1576", "1580", "1584", "1588", "1592", "1596"}, ids(p.Hits))
+	assert.True(t, p.Estimated)
+	assert.Equal(t, int64(500), p.Total)
+
+	// 扫描上限内仍填不满时，Total 收缩到已找到的可见结果数，不再提供更深的分页
+	fetch, keep, _, _ = rawResults(20000, func(id int) bool { return id%20 != 0 })
+	p, err = refillPage(50, 10, pageStarts{}, fetch, keep)
+	require.NoError(t, err)
+	assert.Empty(t, p.Hits)
+	assert.True(t, p.Estimated)
+	assert.Equal(t, int64(100), p.Total)
+
+	_, err = refillPage(1, 10, pageStarts{}, func(int, int) (*search.SearchResult, error) { return nil, errors.New("down") }, keep)
+	assert.Error(t, err)
+}
+
+func TestRefillPageDeepPaging(t *testing.T) {
+	fetch, keep, calls, _ := rawResults(3000, everyThird)
+
+	// 逐页向后翻到很深的位置，结果既不重复也不遗漏，每页只需一两次请求
+	starts := pageStarts{}
+	var seen []string
+	for page := 1; page <= 150; page++ {
+		p, err := refillPage(page, 10, starts, fetch, keep)
+		require.NoError(t, err)
+		require.Len(t, p.Hits, 10, "page %d", page)
+		seen = append(seen, ids(p.Hits)...)
+	}
+	var want []string
+	for id := 0; len(want) < len(seen); id++ {
+		if !everyThird(id) {
+			want = append(want, strconv.Itoa(id))
+		}
+	}
+	assert.Equal(t, want, seen)
+	assert.LessOrEqual(t, *calls, 2*150)
+
+	// 没有起点缓存的实例得到相同的分页边界
+	p, err := refillPage(120, 10, pageStarts{}, fetch, keep)
+	require.NoError(t, err)
+	assert.Equal(t, seen[1190:1200], ids(p.Hits))
+}
+
+func TestRefillCursors(t *testing.T) {
+	r := newRefillCursors()
+	starts := pageStarts{}
+	for p := 2; p <= refillCursorPages+10; p++ {
+		starts[p] = p * 10
+	}
+	r.store("q", starts)
+	loaded := r.load("q")
+	assert.Len(t, loaded, refillCursorPages)
+	page, offset := loaded.resume(10)
+	assert.Equal(t, 1, page, "the shallowest pages are dropped")
+	assert.Equal(t, 0, offset)
+	page, offset = loaded.resume(50)
+	assert.Equal(t, 50, page)
+	assert.Equal(t, 500, offset)
+	page, offset = loaded.resume(200)
+	assert.Equal(t, refillCursorPages+10, page)
+	assert.Equal(t, (refillCursorPages+10)*10, offset)
+	assert.Empty(t, r.load("other"))
+}
@@ -26,9 +26,38 @@ func SetupRouter(h *Handler) *gin.Engine {
 	api := r.Group("/api")
 	{
 		api.GET("/search", h.Search)
+		api.GET("/trending", h.Trending)
+		api.POST("/click", h.Click)
 		api.POST("/index", h.Index)
 		api.GET("/health", h.Health)
 	}
 
+	admin := api.Group("/admin", h.RequireAdmin)
+	{
+		admin.GET("/cache/stats", h.CacheStats)
+		admin.GET("/ranking/profiles", h.RankingProfiles)
+		admin.GET("/explain", h.Explain)
+		admin.GET("/curations", h.ListCurations)
+		admin.POST("/curations", h.CreateCuration)
+		admin.GET("/curations/:id", h.GetCuration)
+		admin.PUT("/curations/:id", h.UpdateCuration)
+		admin.DELETE("/curations/:id", h.DeleteCuration)
+		admin.GET("/filter/rules", h.ListFilterRules)
+		admin.POST("/filter/rules", h.CreateFilterRule)
+		admin.GET("/filter/rules/:id", h.GetFilterRule)
+		admin.PUT("/filter/rules/:id", h.UpdateFilterRule)
+		admin.DELETE("/filter/rules/:id", h.DeleteFilterRule)
+		admin.POST("/filter/dry-run", h.DryRunFilterRules)
+		admin.GET("/filter/blocklists", h.Blocklists)
+		admin.GET("/filter/audit", h.FilterAudit)
+		admin.GET("/filter/audit/rules", h.FilterAuditByRule)
+		admin.GET("/filter/audit/stats", h.FilterAuditStats)
+		admin.GET("/analytics/top-queries", h.TopQueries)
+		admin.GET("/analytics/zero-results", h.ZeroResultQueries)
+		admin.GET("/analytics/latency", h.LatencyPercentiles)
+		admin.GET("/analytics/filter-rate", h.FilterRate)
+		admin.GET("/experiments/:name/report", h.ExperimentReport)
+	}
+
 	return r
 }
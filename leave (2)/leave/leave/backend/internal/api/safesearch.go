@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"search-engine-backend/internal/filter"
+)
+
+// safeSearchCookie 用户选择的安全搜索级别
+const safeSearchCookie = "leave_safe"
+
+// safeSearch returns the SafeSearch level the user asked for. A valid
+// safe parameter is remembered in a cookie; otherwise the cookie, then the
+// configured default is used. The regional floor is applied by the filter.
+func (h *Handler) safeSearch(c *gin.Context) string {
+	if level, ok := filter.ParseSafeSearch(c.Query("safe")); ok {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(safeSearchCookie, level, 365*24*3600, "/", "", false, true)
+		return level
+	}
+	if v, err := c.Cookie(safeSearchCookie); err == nil {
+		if level, ok := filter.ParseSafeSearch(v); ok {
+			return level
+		}
+	}
+	if level, ok := filter.ParseSafeSearch(h.cfg.SafeSearchDefault); ok {
+		return level
+	}
+	return filter.SafeOff
+}
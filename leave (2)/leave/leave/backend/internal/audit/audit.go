@@ -0,0 +1,185 @@
+// Package audit keeps an append-only trail of the search results hidden by
+// the content filter.
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"search-engine-backend/internal/filter"
+	"search-engine-backend/internal/search"
+	"search-engine-backend/internal/storage"
+)
+
+const (
+	batchSize     = 100
+	flushInterval = 2 * time.Second
+	// recordTimeout 缓冲区满时最多等待写入线程腾出空间的时间，超时后丢弃记录
+	recordTimeout = 50 * time.Millisecond
+)
+
+// Stats 审计写入队列的状态，Dropped 不为零说明写入跟不上，需要扩大缓冲区或排查数据库
+type Stats struct {
+	Queued   int    `json:"queued"`
+	Capacity int    `json:"capacity"`
+	Dropped  uint64 `json:"dropped"`
+}
+
+// Service 异步写入审计记录，并按保留期清理过期记录
+type Service struct {
+	db        *storage.DB
+	salt      string
+	retention time.Duration
+
+	entries chan storage.FilterAudit
+	dropped atomic.Uint64
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// NewService starts the writer. Records older than retention are purged
+// by Run; zero keeps them forever.
+func NewService(db *storage.DB, bufferSize int, salt string, retention time.Duration) *Service {
+	s := &Service{
+		db:        db,
+		salt:      salt,
+		retention: retention,
+		entries:   make(chan storage.FilterAudit, bufferSize),
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+// HashQuery returns the salted hash stored for a query, so the trail of a
+// known query can be looked up without storing queries in clear text.
+func (s *Service) HashQuery(query string) string {
+	mac := hmac.New(sha256.New, []byte(s.salt))
+	mac.Write([]byte(search.NormalizeQuery(query)))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// Record queues a hidden result; it implements filter.Auditor. When the
+// buffer is full it waits up to recordTimeout for the writer, then drops the
+// record and counts it in Stats.
+func (s *Service) Record(h filter.Hidden) {
+	if h.Time.IsZero() {
+		h.Time = time.Now()
+	}
+	row := storage.FilterAudit{
+		QueryHash:  s.HashQuery(h.Query),
+		DocID:      h.DocID,
+		URL:        h.URL,
+		Rule:       h.Rule,
+		Category:   h.Category,
+		Region:     h.Region,
+		SafeSearch: h.SafeSearch,
+		CreatedAt:  h.Time,
+	}
+	select {
+	case s.entries <- row:
+		return
+	default:
+	}
+	timer := time.NewTimer(recordTimeout)
+	defer timer.Stop()
+	select {
+	case s.entries <- row:
+	case <-timer.C:
+		if dropped := s.dropped.Add(1); dropped%1000 == 1 {
+			log.Printf("ALERT: filter audit buffer full, %d records dropped so far", dropped)
+		}
+	}
+}
+
+// Stats reports the queue length and the number of dropped records.
+func (s *Service) Stats() Stats {
+	return Stats{Queued: len(s.entries), Capacity: cap(s.entries), Dropped: s.dropped.Load()}
+}
+
+// Close flushes queued records and stops the writer.
+func (s *Service) Close() {
+	s.once.Do(func() {
+		close(s.entries)
+		s.wg.Wait()
+	})
+}
+
+func (s *Service) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	rows := make([]storage.FilterAudit, 0, batchSize)
+	flush := func() {
+		if err := s.db.SaveFilterAudits(rows); err != nil {
+			log.Printf("failed to save %d filter audit records: %v", len(rows), err)
+		}
+		rows = rows[:0]
+	}
+
+	for {
+		select {
+		case row, ok := <-s.entries:
+			if !ok {
+				flush()
+				return
+			}
+			rows = append(rows, row)
+			if len(rows) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Run purges records past the retention period every interval.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	if s.retention <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.Purge(time.Now()); err != nil {
+				log.Printf("failed to purge filter audit records: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Purge deletes the records that are past retention at now.
+func (s *Service) Purge(now time.Time) (int64, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	return s.db.PurgeFilterAudits(now.Add(-s.retention))
+}
+
+// Search returns matching records, newest first, and the total count.
+// Query, if set, is hashed before matching.
+func (s *Service) Search(q storage.AuditQuery, query string) ([]storage.FilterAudit, int64, error) {
+	if query != "" {
+		q.QueryHash = s.HashQuery(query)
+	}
+	return s.db.SearchFilterAudits(q)
+}
+
+func (s *Service) ByRule(from, to time.Time, limit int) ([]storage.RuleCount, error) {
+	return s.db.FilterAuditsByRule(from, to, limit)
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"search-engine-backend/internal/filter"
+	"search-engine-backend/internal/search"
+	"search-engine-backend/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditTrail(t *testing.T) {
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	s := NewService(db, 16, "salt", 24*time.Hour)
+
+	f, err := filter.NewService("")
+	require.NoError(t, err)
+	f.SetAuditor(s)
+	rules, err := filter.NewRuleStore(db, f)
+	require.NoError(t, err)
+	rule := &storage.FilterRule{Kind: storage.FilterRuleKeyword, Value: "casino", Category: "gambling", Creator: "alice"}
+	require.NoError(t, rules.Create(rule))
+
+	docs := []search.Document{
+		{ID: "1", URL: "https://www.adult.com/a"},
+		{ID: "2", URL: "https://example.com/", Title: "online casino"},
+		{ID: "3", URL: "https://example.com/porn", Content: "PORN"},
+		{ID: "4", URL: "https://example.com/ok", Title: "news"},
+	}
+	_, n := f.Filter(docs, filter.Request{Query: "Test  Query", Region: "CN", SafeSearch: filter.SafeOff})
+	assert.Equal(t, 3, n)
+	_, n = f.Filter(docs[:1], filter.Request{Query: "other", Region: "CN"})
+	assert.Equal(t, 1, n)
+	_, n = f.Filter(docs, filter.Request{Query: "abroad", Region: "OTHER"})
+	assert.Equal(t, 0, n)
+	s.Close()
+
+	now := time.Now()
+	all := storage.AuditQuery{From: now.Add(-time.Hour), To: now.Add(time.Hour), Limit: 10}
+	records, total, err := s.Search(all, "test query")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, total, "query matched by hash after normalization")
+	byDoc := make(map[string]storage.FilterAudit)
+	for _, r := range records {
+		assert.Equal(t, "CN", r.Region)
+		assert.NotContains(t, r.QueryHash, "test")
+		byDoc[r.DocID] = r
+	}
+	assert.Equal(t, "domain:adult.com", byDoc["1"].Rule)
+	assert.Equal(t, "https://www.adult.com/a", byDoc["1"].URL)
+	assert.Equal(t, "rule:1", byDoc["2"].Rule)
+	assert.Equal(t, "gambling", byDoc["2"].Category)
+	assert.Equal(t, "keyword:porn", byDoc["3"].Rule)
+
+	q := all
+	q.Rule = "domain:adult.com"
+	_, total, err = s.Search(q, "")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+
+	counts, err := s.ByRule(all.From, all.To, 10)
+	require.NoError(t, err)
+	require.Len(t, counts, 3)
+	assert.Equal(t, storage.RuleCount{Rule: "domain:adult.com", Category: filter.DefaultCategory, Count: 2, Docs: 1}, counts[0])
+
+	// 保留期之外的记录被清理
+	purged, err := s.Purge(now.Add(48 * time.Hour))
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, purged)
+}
+
+func TestRecordWhenFull(t *testing.T) {
+	// 不启动写入线程，缓冲区满后等待超时并计入丢弃数
+	s := &Service{salt: "salt", entries: make(chan storage.FilterAudit, 1)}
+	s.Record(filter.Hidden{DocID: "1"})
+	start := time.Now()
+	s.Record(filter.Hidden{DocID: "2"})
+	assert.GreaterOrEqual(t, time.Since(start), recordTimeout, "waits for the writer before dropping")
+	assert.Equal(t, Stats{Queued: 1, Capacity: 1, Dropped: 1}, s.Stats())
+
+	// 等待期间写入线程腾出空间则不丢弃
+	go func() {
+		time.Sleep(recordTimeout / 5)
+		<-s.entries
+	}()
+	s.Record(filter.Hidden{DocID: "3"})
+	assert.Equal(t, Stats{Queued: 1, Capacity: 1, Dropped: 1}, s.Stats())
+	assert.Equal(t, "3", (<-s.entries).DocID)
+}
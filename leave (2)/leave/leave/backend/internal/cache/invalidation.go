@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// InvalidationChannel 用于在多实例之间广播本地缓存失效消息，消息内容为 key 前缀，
+// 前缀的代数变化时附带新代数 ("<前缀>\n<代数>")
+const InvalidationChannel = "cache:invalidate"
+
+// PublishInvalidation asks every instance to purge local entries under prefix.
+func PublishInvalidation(ctx context.Context, client *redis.Client, prefix string) error {
+	return client.Publish(ctx, InvalidationChannel, prefix).Err()
+}
+
+// publishGeneration is PublishInvalidation that also carries the new
+// generation of prefix, so that other instances need not read it back.
+func publishGeneration(ctx context.Context, client *redis.Client, prefix string, gen int64) error {
+	return client.Publish(ctx, InvalidationChannel, prefix+"\n"+strconv.FormatInt(gen, 10)).Err()
+}
+
+// parseInvalidation splits a message into the prefix and, if present, the
+// new generation.
+func parseInvalidation(payload string) (prefix string, gen int64, ok bool) {
+	i := strings.LastIndexByte(payload, '\n')
+	if i < 0 {
+		return payload, 0, false
+	}
+	gen, err := strconv.ParseInt(payload[i+1:], 10, 64)
+	if err != nil {
+		return payload, 0, false
+	}
+	return payload[:i], gen, true
+}
+
+// generations 本实例已知的各前缀代数，由失效消息更新；订阅断开重连后清空，
+// 期间错过的消息通过重新读取 Redis 补上
+type generations struct {
+	mu sync.RWMutex
+	m  map[string]int64
+}
+
+func newGenerations() *generations {
+	return &generations{m: make(map[string]int64)}
+}
+
+func (g *generations) get(prefix string) (int64, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	gen, ok := g.m[prefix]
+	return gen, ok
+}
+
+// observe records gen unless a newer one is already known; messages and
+// Redis reads may arrive out of order.
+func (g *generations) observe(prefix string, gen int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if cur, ok := g.m[prefix]; !ok || gen > cur {
+		g.m[prefix] = gen
+	}
+}
+
+func (g *generations) reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.m = make(map[string]int64)
+}
+
+// subscribeInvalidation purges the local tier and tracks generations from
+// invalidation messages. After a reconnect, messages may have been missed,
+// so the local tier is dropped and generations are read from Redis again.
+// It runs until ctx is cancelled.
+func (c *CacheService) subscribeInvalidation(ctx context.Context) {
+	pubsub := c.client.Subscribe(ctx, InvalidationChannel)
+	go func() {
+		defer pubsub.Close()
+		ch := pubsub.ChannelWithSubscriptions(ctx, 100)
+		subscribed := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					log.Printf("cache invalidation subscription closed")
+					return
+				}
+				switch msg := msg.(type) {
+				case *redis.Subscription:
+					if subscribed {
+						log.Printf("cache invalidation subscription restored, dropping local state")
+						c.gens.reset()
+						c.local.Purge("")
+					}
+					subscribed = true
+				case *redis.Message:
+					prefix, gen, hasGen := parseInvalidation(msg.Payload)
+					if hasGen {
+						c.gens.observe(prefix, gen)
+					}
+					c.local.Purge(prefix)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LocalCache is a size-bounded in-process LRU with a per-entry TTL.
+// Values are stored as serialized bytes so callers never share mutable state.
+type LocalCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	ll       *list.List
+	items    map[string]*list.Element
+	now      func() time.Time
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type localEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// LocalCacheStats 本地缓存命中统计
+type LocalCacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Size   int    `json:"size"`
+}
+
+func NewLocalCache(capacity int, ttl time.Duration) *LocalCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LocalCache{
+		capacity: capacity,
+		ttl:      ttl,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+func (c *LocalCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+	entry := el.Value.(*localEntry)
+	if c.now().After(entry.expires) {
+		c.removeElement(el)
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	c.hits.Add(1)
+	return entry.value, true
+}
+
+func (c *LocalCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*localEntry)
+		entry.value = value
+		entry.expires = expires
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&localEntry{key: key, value: value, expires: expires})
+	for c.ll.Len() > c.capacity {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// Purge removes every entry whose key starts with prefix; "" clears the cache.
+func (c *LocalCache) Purge(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, el := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.removeElement(el)
+		}
+	}
+}
+
+func (c *LocalCache) Stats() LocalCacheStats {
+	c.mu.Lock()
+	size := c.ll.Len()
+	c.mu.Unlock()
+	return LocalCacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Size:   size,
+	}
+}
+
+func (c *LocalCache) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*localEntry).key)
+}
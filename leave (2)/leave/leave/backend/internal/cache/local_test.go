@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalCache(t *testing.T) {
+	now := time.Now()
+	c := NewLocalCache(2, time.Second)
+	c.now = func() time.Time { return now }
+
+	c.Set("search:a", []byte("1"))
+	c.Set("search:b", []byte("2"))
+	_, ok := c.Get("search:a")
+	assert.True(t, ok)
+
+	// b 最久未使用，被淘汰
+	c.Set("search:c", []byte("3"))
+	_, ok = c.Get("search:b")
+	assert.False(t, ok)
+
+	now = now.Add(2 * time.Second)
+	_, ok = c.Get("search:a")
+	assert.False(t, ok, "expired entries miss")
+
+	c.Set("search:d", []byte("4"))
+	c.Set("other:e", []byte("5"))
+	c.Purge("search:")
+	_, ok = c.Get("search:d")
+	assert.False(t, ok)
+	_, ok = c.Get("other:e")
+	assert.True(t, ok)
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(3), stats.Misses)
+	assert.Equal(t, 1, stats.Size)
+}
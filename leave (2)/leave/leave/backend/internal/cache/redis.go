@@ -2,56 +2,256 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
+// Options configures a CacheService.
+type Options struct {
+	// Namespace 作为所有 key 的前缀，例如 "leave:"
+	Namespace  string
+	Serializer Serializer
+	// LocalSize 为 0 时不启用进程内 L1 缓存
+	LocalSize int
+	LocalTTL  time.Duration
+	// OnError 接收缓存读写失败；默认写日志
+	OnError func(op, key string, err error)
+}
+
+// CacheService is the two-tier (in-process L1 + Redis L2) cache layer.
+// Use the package-level Get, Set and GetOrSet for typed access.
 type CacheService struct {
-	client *redis.Client
+	client     *redis.Client
+	namespace  string
+	serializer Serializer
+	local      *LocalCache
+	// gens 订阅失效消息时缓存各前缀的代数，为空时每次读取 Redis
+	gens    *generations
+	onError func(op, key string, err error)
+	cancel  context.CancelFunc
+	stats   *remoteStats
+	now     func() time.Time
+}
+
+type remoteStats struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	errors atomic.Uint64
 }
 
-func NewCacheService(addr, password string) *CacheService {
+// Stats 缓存命中与错误统计
+type Stats struct {
+	Local        LocalCacheStats `json:"local"`
+	RemoteHits   uint64          `json:"remote_hits"`
+	RemoteMisses uint64          `json:"remote_misses"`
+	Errors       uint64          `json:"errors"`
+}
+
+func NewCacheService(addr, password string, opts Options) *CacheService {
 	rdb := redis.NewClient(&redis.Options{
 		Addr:     addr,
 		Password: password,
 		DB:       0,
 	})
-	return &CacheService{client: rdb}
+	return newCacheService(rdb, opts)
 }
 
-// GetOrSet implements a cache-aside pattern
-func (c *CacheService) GetOrSet(ctx context.Context, key string, ttl time.Duration, fetch func() (interface{}, error)) (interface{}, error) {
-	// 1. Try Get
-	val, err := c.client.Get(ctx, key).Result()
-	if err == nil {
-		// Cache Hit
-		// Record hit metric here (e.g. Prometheus)
-		return val, nil
+func newCacheService(client *redis.Client, opts Options) *CacheService {
+	c := &CacheService{
+		client:     client,
+		namespace:  opts.Namespace,
+		serializer: opts.Serializer,
+		onError:    opts.OnError,
+		cancel:     func() {},
+		stats:      &remoteStats{},
+		now:        time.Now,
+	}
+	if c.serializer == nil {
+		c.serializer = JSONSerializer{}
+	}
+	if c.onError == nil {
+		c.onError = func(op, key string, err error) {
+			log.Printf("cache %s %s: %v", op, key, err)
+		}
 	}
+	if opts.LocalSize > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.local = NewLocalCache(opts.LocalSize, opts.LocalTTL)
+		c.gens = newGenerations()
+		c.subscribeInvalidation(ctx)
+		c.cancel = cancel
+	}
+	return c
+}
+
+// Sub returns a view under a nested namespace. It shares the Redis client,
+// serializer and error handler but has no local tier, which suits large or
+// long-lived entries such as the stale search tier.
+func (c *CacheService) Sub(namespace string) *CacheService {
+	return &CacheService{
+		client:     c.client,
+		namespace:  c.namespace + namespace,
+		serializer: c.serializer,
+		onError:    c.onError,
+		cancel:     func() {},
+		stats:      c.stats,
+		now:        c.now,
+	}
+}
+
+// Key returns the namespaced Redis key.
+func (c *CacheService) Key(key string) string {
+	return c.namespace + key
+}
 
-	// 2. Cache Miss - Fetch Data
-	data, err := fetch()
+func (c *CacheService) Close() error {
+	c.cancel()
+	return c.client.Close()
+}
+
+func (c *CacheService) Stats() Stats {
+	s := Stats{
+		RemoteHits:   c.stats.hits.Load(),
+		RemoteMisses: c.stats.misses.Load(),
+		Errors:       c.stats.errors.Load(),
+	}
+	if c.local != nil {
+		s.Local = c.local.Stats()
+	}
+	return s
+}
+
+// generationKey 前缀的代数计数器，Invalidate 时递增
+func (c *CacheService) generationKey(prefix string) string {
+	return c.Key("gen:" + prefix)
+}
+
+// Generation returns the current generation of prefix, 0 before the first
+// Invalidate. Keys that embed it are never read again once the prefix is
+// invalidated, on any instance, so Redis entries need not be deleted.
+// With a local tier the generation is kept in memory and updated from
+// invalidation messages; Redis is read only the first time and after the
+// subscription reconnects.
+func (c *CacheService) Generation(ctx context.Context, prefix string) (int64, error) {
+	full := c.Key(prefix)
+	if c.gens != nil {
+		if gen, ok := c.gens.get(full); ok {
+			return gen, nil
+		}
+	}
+	gen, err := c.client.Get(ctx, c.generationKey(prefix)).Int64()
+	if errors.Is(err, redis.Nil) {
+		gen, err = 0, nil
+	}
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	if c.gens != nil {
+		c.gens.observe(full, gen)
 	}
+	return gen, nil
+}
 
-	// 3. Set Cache (Async)
-	go func() {
-		bytes, _ := json.Marshal(data)
-		c.client.Set(context.Background(), key, bytes, ttl)
-	}()
+// Invalidate bumps the generation of prefix, drops every local entry under
+// it and tells the other instances to do the same. Old Redis entries are
+// orphaned and expire on their own TTL.
+func (c *CacheService) Invalidate(ctx context.Context, prefix string) error {
+	full := c.Key(prefix)
+	if c.local != nil {
+		c.local.Purge(full)
+	}
+	gen, err := c.client.Incr(ctx, c.generationKey(prefix)).Result()
+	if err != nil {
+		return err
+	}
+	if c.gens != nil {
+		c.gens.observe(full, gen)
+	}
+	return publishGeneration(ctx, c.client, full, gen)
+}
+
+func (c *CacheService) report(op, key string, err error) {
+	c.stats.errors.Add(1)
+	c.onError(op, key, err)
+}
+
+// getBytes reads L1 then L2, back-filling L1 on an L2 hit.
+func (c *CacheService) getBytes(ctx context.Context, key string) ([]byte, bool, error) {
+	full := c.Key(key)
+	if c.local != nil {
+		if data, ok := c.local.Get(full); ok {
+			return data, true, nil
+		}
+	}
+
+	data, err := c.client.Get(ctx, full).Bytes()
+	if errors.Is(err, redis.Nil) {
+		c.stats.misses.Add(1)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	c.stats.hits.Add(1)
+	if c.local != nil {
+		c.local.Set(full, data)
+	}
+	return data, true, nil
+}
+
+func (c *CacheService) setBytes(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	full := c.Key(key)
+	if c.local != nil {
+		c.local.Set(full, data)
+	}
+	return c.client.Set(ctx, full, data, ttl).Err()
+}
 
-	return data, nil
+// Get reads a typed value. A missing key returns ok == false and no error.
+func Get[T any](ctx context.Context, c *CacheService, key string) (T, bool, error) {
+	var value T
+	data, ok, err := c.getBytes(ctx, key)
+	if err != nil || !ok {
+		return value, false, err
+	}
+	if err := c.serializer.Unmarshal(data, &value); err != nil {
+		return value, false, err
+	}
+	return value, true, nil
 }
 
-// AddHotQuery adds a query to the hot list (Sorted Set)
-func (c *CacheService) AddHotQuery(ctx context.Context, query string) {
-	c.client.ZIncrBy(ctx, "hot_queries", 1, query)
+// Set writes a typed value to both tiers.
+func Set[T any](ctx context.Context, c *CacheService, key string, value T, ttl time.Duration) error {
+	data, err := c.serializer.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.setBytes(ctx, key, data, ttl)
 }
 
-// GetHotQueries retrieves top N queries
-func (c *CacheService) GetHotQueries(ctx context.Context, n int64) ([]string, error) {
-	return c.client.ZRevRange(ctx, "hot_queries", 0, n-1).Result()
+// GetOrSet implements a cache-aside pattern. Errors from fetch are returned;
+// cache read/write failures are passed to Options.OnError and do not fail
+// the call, so a Redis outage degrades to uncached reads.
+func GetOrSet[T any](ctx context.Context, c *CacheService, key string, ttl time.Duration, fetch func() (T, error)) (T, error) {
+	value, ok, err := Get[T](ctx, c, key)
+	if err != nil {
+		c.report("get", key, err)
+	}
+	if ok {
+		return value, nil
+	}
+
+	value, err = fetch()
+	if err != nil {
+		return value, err
+	}
+
+	if err := Set(ctx, c, key, value, ttl); err != nil {
+		c.report("set", key, err)
+	}
+	return value, nil
 }
@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cachedItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func newTestCache(t *testing.T) (*CacheService, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	c := NewCacheService(mr.Addr(), "", Options{Namespace: "test:"})
+	t.Cleanup(func() { c.Close() })
+	return c, mr
+}
+
+func TestGetOrSetTyped(t *testing.T) {
+	c, mr := newTestCache(t)
+	ctx := context.Background()
+
+	calls := 0
+	fetch := func() (cachedItem, error) {
+		calls++
+		return cachedItem{Name: "go", Count: 3}, nil
+	}
+
+	first, err := GetOrSet(ctx, c, "item", time.Minute, fetch)
+	require.NoError(t, err)
+	second, err := GetOrSet(ctx, c, "item", time.Minute, fetch)
+	require.NoError(t, err)
+
+	// 命中与未命中返回相同的类型化结果
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, calls)
+	assert.True(t, mr.Exists("test:item"), "keys carry the namespace prefix")
+}
+
+func TestGetOrSetReportsCacheErrors(t *testing.T) {
+	c, mr := newTestCache(t)
+	var reported []string
+	c.onError = func(op, key string, err error) { reported = append(reported, op) }
+	mr.Close()
+
+	v, err := GetOrSet(context.Background(), c, "item", time.Minute, func() (int, error) { return 7, nil })
+	require.NoError(t, err, "a Redis outage must not fail the call")
+	assert.Equal(t, 7, v)
+	assert.Equal(t, []string{"get", "set"}, reported)
+	assert.Equal(t, uint64(2), c.Stats().Errors)
+}
+
+func TestGetOrSetReturnsFetchError(t *testing.T) {
+	c, _ := newTestCache(t)
+	boom := errors.New("boom")
+
+	_, err := GetOrSet(context.Background(), c, "item", time.Minute, func() (int, error) { return 0, boom })
+	assert.ErrorIs(t, err, boom)
+}
+
+func TestHotQueriesDecay(t *testing.T) {
+	c, _ := newTestCache(t)
+	ctx := context.Background()
+	now := time.Date(2026, 10, 18, 12, 30, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	// 两小时前搜了三次 "old"，当前小时搜了两次 "new"
+	now = now.Add(-2 * time.Hour)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, c.AddHotQuery(ctx, "old"))
+	}
+	now = now.Add(2 * time.Hour)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, c.AddHotQuery(ctx, "new"))
+	}
+
+	hour, err := c.GetHotQueries(ctx, "hour", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []HotQuery{{"new", 2}, {"old", 0.75}}, hour)
+
+	day, err := c.GetHotQueries(ctx, "day", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []HotQuery{{"old", 3}, {"new", 2}}, day)
+
+	_, err = c.GetHotQueries(ctx, "year", 10)
+	assert.ErrorIs(t, err, ErrUnknownWindow)
+}
+
+func TestPopEndedSessions(t *testing.T) {
+	c, _ := newTestCache(t)
+	ctx := context.Background()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.RecordSessionQuery(ctx, "a", "golang"))
+	require.NoError(t, c.RecordSessionQuery(ctx, "a", "go tutorial"))
+	now = now.Add(20 * time.Minute)
+	require.NoError(t, c.RecordSessionQuery(ctx, "b", "rust"))
+
+	now = now.Add(15 * time.Minute)
+	sessions, err := c.PopEndedSessions(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"golang", "go tutorial"}}, sessions, "only sessions idle for SessionIdle have ended")
+
+	sessions, err = c.PopEndedSessions(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, sessions, "ended sessions are mined once")
+}
+
+func TestGenerationKeptInMemory(t *testing.T) {
+	mr := miniredis.RunT(t)
+	newInstance := func() *CacheService {
+		c := NewCacheService(mr.Addr(), "", Options{Namespace: "test:", LocalSize: 10, LocalTTL: time.Minute})
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+	a, b := newInstance(), newInstance()
+	ctx := context.Background()
+
+	gen, err := b.Generation(ctx, "search:")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), gen)
+
+	// 读过一次之后不再访问 Redis
+	mr.Set("test:gen:search:", "7")
+	gen, err = b.Generation(ctx, "search:")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), gen)
+
+	// 新代数随失效消息到达其他实例
+	require.NoError(t, a.Invalidate(ctx, "search:"))
+	gen, err = a.Generation(ctx, "search:")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), gen)
+	assert.Eventually(t, func() bool {
+		gen, err := b.Generation(ctx, "search:")
+		return err == nil && gen == 8
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestParseInvalidation(t *testing.T) {
+	prefix, gen, ok := parseInvalidation("leave:search:\n12")
+	assert.True(t, ok)
+	assert.Equal(t, "leave:search:", prefix)
+	assert.Equal(t, int64(12), gen)
+
+	prefix, _, ok = parseInvalidation("leave:related:")
+	assert.False(t, ok)
+	assert.Equal(t, "leave:related:", prefix)
+}
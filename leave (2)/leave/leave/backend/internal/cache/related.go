@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 会话：同一匿名用户相邻两次搜索间隔不超过 SessionIdle 视为同一会话
+const (
+	SessionIdle   = 30 * time.Minute
+	sessionMaxLen = 20
+	// sessionTTL 需大于 SessionIdle 加上挖掘任务的间隔，保证会话结束后仍可读取
+	sessionTTL      = 6 * time.Hour
+	cooccurrenceTTL = 30 * 24 * time.Hour
+	relatedTTL      = 7 * 24 * time.Hour
+)
+
+func (c *CacheService) sessionKey(session string) string {
+	return c.Key("session:" + session)
+}
+
+// RecordSessionQuery appends query to the search sequence of a session.
+func (c *CacheService) RecordSessionQuery(ctx context.Context, session, query string) error {
+	key := c.sessionKey(session)
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, query)
+		pipe.LTrim(ctx, key, -sessionMaxLen, -1)
+		pipe.Expire(ctx, key, sessionTTL)
+		pipe.ZAdd(ctx, c.Key("sessions:active"), &redis.Z{Score: float64(c.now().Unix()), Member: session})
+		return nil
+	})
+	return err
+}
+
+// PopEndedSessions removes up to n sessions that have been idle for
+// SessionIdle and returns their search sequences.
+func (c *CacheService) PopEndedSessions(ctx context.Context, n int64) ([][]string, error) {
+	active := c.Key("sessions:active")
+	cutoff := c.now().Add(-SessionIdle).Unix()
+	ids, err := c.client.ZRangeByScore(ctx, active, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(cutoff, 10),
+		Count: n,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sequences := make([][]string, 0, len(ids))
+	for _, id := range ids {
+		var seq *redis.StringSliceCmd
+		_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			seq = pipe.LRange(ctx, c.sessionKey(id), 0, -1)
+			pipe.Del(ctx, c.sessionKey(id))
+			pipe.ZRem(ctx, active, id)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(seq.Val()) > 0 {
+			sequences = append(sequences, seq.Val())
+		}
+	}
+	return sequences, nil
+}
+
+// AddCooccurrences adds weight to each query pair in both directions.
+func (c *CacheService) AddCooccurrences(ctx context.Context, pairs map[[2]string]float64) error {
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for p, w := range pairs {
+			for _, d := range [][2]string{p, {p[1], p[0]}} {
+				key := c.Key("cooc:" + d[0])
+				pipe.ZIncrBy(ctx, key, w, d[1])
+				pipe.Expire(ctx, key, cooccurrenceTTL)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// Cooccurrences returns the n queries most often searched in the same
+// session as query.
+func (c *CacheService) Cooccurrences(ctx context.Context, query string, n int64) ([]HotQuery, error) {
+	zs, err := c.client.ZRevRangeWithScores(ctx, c.Key("cooc:"+query), 0, n-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	queries := make([]HotQuery, 0, len(zs))
+	for _, z := range zs {
+		queries = append(queries, HotQuery{Query: z.Member.(string), Score: z.Score})
+	}
+	return queries, nil
+}
+
+// SetRelated stores the precomputed related searches of a query.
+func (c *CacheService) SetRelated(ctx context.Context, query string, related []string) error {
+	return Set(ctx, c, "related:"+query, related, relatedTTL)
+}
+
+// Related returns the precomputed related searches of a query.
+func (c *CacheService) Related(ctx context.Context, query string) ([]string, error) {
+	related, _, err := Get[[]string](ctx, c, "related:"+query)
+	return related, err
+}
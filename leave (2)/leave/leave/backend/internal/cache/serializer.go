@@ -0,0 +1,20 @@
+package cache
+
+import "encoding/json"
+
+// Serializer converts cached values to and from bytes.
+type Serializer interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONSerializer is the default Serializer.
+type JSONSerializer struct{}
+
+func (JSONSerializer) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONSerializer) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
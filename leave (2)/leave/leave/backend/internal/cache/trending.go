@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrUnknownWindow is returned for a trending window that is not configured.
+var ErrUnknownWindow = errors.New("unknown trending window")
+
+// TrendingWindow 热搜时间窗口：按 Bucket 粒度分桶计数，
+// 取最近 Span 个桶加权求和，每早一个桶权重乘以 Decay。
+type TrendingWindow struct {
+	Bucket time.Duration
+	Span   int
+	Decay  float64
+}
+
+// TrendingWindows are the windows accepted by GetHotQueries.
+var TrendingWindows = map[string]TrendingWindow{
+	"hour": {Bucket: time.Hour, Span: 6, Decay: 0.5},
+	"day":  {Bucket: 24 * time.Hour, Span: 7, Decay: 0.7},
+}
+
+// trendingSnapshotTTL 合并结果临时 key 的过期时间
+const trendingSnapshotTTL = time.Minute
+
+type HotQuery struct {
+	Query string  `json:"query"`
+	Score float64 `json:"score"`
+}
+
+func (c *CacheService) trendingKey(name string, w TrendingWindow, t time.Time) string {
+	return c.Key(fmt.Sprintf("trending:%s:%d", name, t.Unix()/int64(w.Bucket/time.Second)))
+}
+
+// AddHotQuery counts query in the current bucket of every trending window.
+func (c *CacheService) AddHotQuery(ctx context.Context, query string) error {
+	now := c.now()
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for name, w := range TrendingWindows {
+			key := c.trendingKey(name, w, now)
+			pipe.ZIncrBy(ctx, key, 1, query)
+			pipe.Expire(ctx, key, w.Bucket*time.Duration(w.Span+1))
+		}
+		return nil
+	})
+	return err
+}
+
+// GetHotQueries retrieves the top n queries of a window with time decay applied.
+func (c *CacheService) GetHotQueries(ctx context.Context, window string, n int64) ([]HotQuery, error) {
+	w, ok := TrendingWindows[window]
+	if !ok {
+		return nil, ErrUnknownWindow
+	}
+
+	now := c.now()
+	store := &redis.ZStore{Aggregate: "SUM"}
+	for i := 0; i < w.Span; i++ {
+		store.Keys = append(store.Keys, c.trendingKey(window, w, now.Add(-time.Duration(i)*w.Bucket)))
+		store.Weights = append(store.Weights, math.Pow(w.Decay, float64(i)))
+	}
+
+	dest := c.Key("trending:" + window + ":snapshot")
+	var rangeCmd *redis.ZSliceCmd
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZUnionStore(ctx, dest, store)
+		pipe.Expire(ctx, dest, trendingSnapshotTTL)
+		rangeCmd = pipe.ZRevRangeWithScores(ctx, dest, 0, n-1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make([]HotQuery, 0, len(rangeCmd.Val()))
+	for _, z := range rangeCmd.Val() {
+		queries = append(queries, HotQuery{Query: z.Member.(string), Score: z.Score})
+	}
+	return queries, nil
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -10,6 +13,62 @@ type Config struct {
 	RedisAddr        string
 	RedisPassword    string
 	JiebaDictPath    string
+
+	// ES 容错：超时、熔断与过期缓存
+	SearchTimeout    time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	StaleCacheTTL    time.Duration
+
+	// 缓存层：Redis key 前缀与进程内 L1 缓存
+	CacheNamespace string
+	LocalCacheSize int
+	LocalCacheTTL  time.Duration
+
+	// 查询日志与搜索分析
+	DatabasePath string
+	// AnalyticsSalt 匿名化客户端 ID 与签名匿名 ID cookie 的密钥，所有实例必须配置同一个值，
+	// 否则同一用户在各实例的匿名 ID 和实验分组不一致。未配置时启动失败；
+	// 单实例部署可改为设置 AnalyticsSaltPath，首次启动随机生成并保存到该文件
+	AnalyticsSalt     string
+	AnalyticsSaltPath string
+	// GeoIPDatabasePath 离线 IP 库 (ip2region .xdb 或 MaxMind .mmdb)，为空时只识别本地和内网地址
+	GeoIPDatabasePath string
+	// RelatedMineInterval 相关搜索挖掘任务的执行间隔
+	RelatedMineInterval time.Duration
+
+	// 排序方案配置文件 (JSON)，修改后自动重新加载
+	RankingProfilesPath string
+	ConfigPollInterval  time.Duration
+
+	// 查询改写/跳转/拒绝规则文件 (JSON)，修改后自动重新加载
+	QueryRulesPath string
+
+	// EmbeddingDims 混合检索的向量维度，为 0 时只做文本检索
+	EmbeddingDims int
+
+	// FilterKeywordsPath 过滤关键词文件或目录，为空时使用内置词表
+	FilterKeywordsPath string
+	// FilterAllowedDomains 白名单域名（含子域名），优先于屏蔽域名
+	FilterAllowedDomains []string
+	// FilterBlocklists 定期导入的屏蔽列表，格式 name=format:path，format 为 hosts、adblock 或 domains
+	FilterBlocklists       []string
+	FilterBlocklistRefresh time.Duration
+	// FilterPoliciesPath 地区过滤策略文件 (JSON)，为空时只在中国大陆过滤
+	FilterPoliciesPath string
+	// SafeSearchDefault 用户未选择时的安全搜索级别：off、moderate 或 strict
+	SafeSearchDefault string
+	// FilterAuditRetention 过滤审计记录的保留期，为 0 时永久保留
+	FilterAuditRetention time.Duration
+
+	// ClassifierModelPath 内容分类模型 (cmd/trainclassifier 生成)，为空时不分类
+	ClassifierModelPath string
+	// ClassifierThreshold 报告分类的最低置信度；ClassifierThresholds 按分类覆盖，格式 adult=0.95
+	ClassifierThreshold  float64
+	ClassifierThresholds []string
+
+	// AdminToken 管理接口令牌；为空时禁用管理接口
+	AdminToken string
 }
 
 func Load() *Config {
@@ -19,6 +78,37 @@ func Load() *Config {
 		RedisAddr:        getEnv("REDIS_ADDR", "localhost:6379"),
 		RedisPassword:    getEnv("REDIS_PASSWORD", ""),
 		JiebaDictPath:    getEnv("JIEBA_DICT_PATH", "dict"),
+		SearchTimeout:    getEnvDuration("SEARCH_TIMEOUT", 3*time.Second),
+		BreakerThreshold: getEnvInt("BREAKER_THRESHOLD", 5),
+		BreakerCooldown:  getEnvDuration("BREAKER_COOLDOWN", 30*time.Second),
+		StaleCacheTTL:    getEnvDuration("STALE_CACHE_TTL", 24*time.Hour),
+		CacheNamespace:   getEnv("CACHE_NAMESPACE", "leave:"),
+		LocalCacheSize:   getEnvInt("LOCAL_CACHE_SIZE", 1000),
+		LocalCacheTTL:    getEnvDuration("LOCAL_CACHE_TTL", 10*time.Second),
+		DatabasePath:     getEnv("DATABASE_PATH", "leave.db"),
+		AnalyticsSalt:    getEnv("ANALYTICS_SALT", ""),
+
+		AnalyticsSaltPath: getEnv("ANALYTICS_SALT_PATH", ""),
+
+		RelatedMineInterval: getEnvDuration("RELATED_MINE_INTERVAL", 10*time.Minute),
+
+		GeoIPDatabasePath: getEnv("GEOIP_DB_PATH", ""),
+
+		RankingProfilesPath:    getEnv("RANKING_PROFILES_PATH", ""),
+		ConfigPollInterval:     getEnvDuration("CONFIG_POLL_INTERVAL", 10*time.Second),
+		QueryRulesPath:         getEnv("QUERY_RULES_PATH", ""),
+		EmbeddingDims:          getEnvInt("EMBEDDING_DIMS", 0),
+		FilterKeywordsPath:     getEnv("FILTER_KEYWORDS_PATH", ""),
+		FilterAllowedDomains:   getEnvList("FILTER_ALLOWED_DOMAINS"),
+		FilterBlocklists:       getEnvList("FILTER_BLOCKLISTS"),
+		FilterBlocklistRefresh: getEnvDuration("FILTER_BLOCKLIST_REFRESH", time.Hour),
+		FilterPoliciesPath:     getEnv("FILTER_POLICIES_PATH", ""),
+		SafeSearchDefault:      getEnv("SAFE_SEARCH_DEFAULT", "off"),
+		FilterAuditRetention:   getEnvDuration("FILTER_AUDIT_RETENTION", 180*24*time.Hour),
+		ClassifierModelPath:    getEnv("CLASSIFIER_MODEL_PATH", ""),
+		ClassifierThreshold:    getEnvFloat("CLASSIFIER_THRESHOLD", 0.9),
+		ClassifierThresholds:   getEnvList("CLASSIFIER_THRESHOLDS"),
+		AdminToken:             getEnv("ADMIN_TOKEN", ""),
 	}
 }
 
@@ -28,3 +118,41 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	if value, ok := os.LookupEnv(key); ok {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if value, ok := os.LookupEnv(key); ok {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, ok := os.LookupEnv(key); ok {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
+
+// getEnvList 读取逗号分隔的列表，忽略空项
+func getEnvList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
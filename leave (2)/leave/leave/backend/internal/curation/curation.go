@@ -0,0 +1,224 @@
+// Package curation manages pinned and hidden search results per query.
+package curation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"search-engine-backend/internal/search"
+	"search-engine-backend/internal/storage"
+)
+
+// ErrInvalidRule is returned when a rule fails validation.
+var ErrInvalidRule = errors.New("invalid curation rule")
+
+// Service 缓存全部规则，增删改后重新加载；Watch 同步其他实例的修改
+type Service struct {
+	db *storage.DB
+
+	mu       sync.RWMutex
+	rules    []storage.CurationRule
+	version  string
+	onChange []func()
+}
+
+func NewService(db *storage.DB) (*Service, error) {
+	s := &Service{db: db}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Service) reload() error {
+	version, err := s.db.CurationRulesVersion()
+	if err != nil {
+		return err
+	}
+	rules, err := s.db.ListCurationRules()
+	if err != nil {
+		return err
+	}
+	// 匹配优先级：exact > prefix > contains，同类型中模式越长越优先
+	sort.SliceStable(rules, func(i, j int) bool {
+		pi, pj := matchPriority(rules[i].Match), matchPriority(rules[j].Match)
+		if pi != pj {
+			return pi < pj
+		}
+		return len(rules[i].Pattern) > len(rules[j].Pattern)
+	})
+
+	s.mu.Lock()
+	s.rules = rules
+	s.version = version
+	callbacks := s.onChange
+	s.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn()
+	}
+	return nil
+}
+
+// Watch polls the rule table every interval and reloads the rules when
+// another instance changed them. The change callbacks run on every
+// instance, so results curated with the old rules are dropped everywhere.
+func (s *Service) Watch(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				version, err := s.db.CurationRulesVersion()
+				if err != nil {
+					log.Printf("failed to check curation rules: %v", err)
+					continue
+				}
+				s.mu.RLock()
+				changed := version != s.version
+				s.mu.RUnlock()
+				if !changed {
+					continue
+				}
+				if err := s.reload(); err != nil {
+					log.Printf("failed to reload curation rules: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func matchPriority(match string) int {
+	switch match {
+	case storage.MatchExact:
+		return 0
+	case storage.MatchPrefix:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// OnChange registers fn to run after the rules change.
+func (s *Service) OnChange(fn func()) {
+	s.mu.Lock()
+	s.onChange = append(s.onChange, fn)
+	s.mu.Unlock()
+}
+
+// Lookup returns the curation of the highest-priority rule matching a
+// normalized query.
+func (s *Service) Lookup(query string) (search.Curation, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, r := range s.rules {
+		if !matches(r, query) {
+			continue
+		}
+		c := search.Curation{Hidden: r.Hidden}
+		for _, p := range r.Pins {
+			c.Pins = append(c.Pins, search.Pin{DocID: p.DocID, Position: p.Position})
+		}
+		return c, true
+	}
+	return search.Curation{}, false
+}
+
+func matches(r storage.CurationRule, query string) bool {
+	switch r.Match {
+	case storage.MatchPrefix:
+		return strings.HasPrefix(query, r.Pattern)
+	case storage.MatchContains:
+		return strings.Contains(query, r.Pattern)
+	default:
+		return query == r.Pattern
+	}
+}
+
+func (s *Service) List() []storage.CurationRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rules := make([]storage.CurationRule, len(s.rules))
+	copy(rules, s.rules)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+func (s *Service) Get(id uint) (*storage.CurationRule, error) {
+	return s.db.GetCurationRule(id)
+}
+
+func (s *Service) Create(rule *storage.CurationRule) error {
+	if err := validate(rule); err != nil {
+		return err
+	}
+	if err := s.db.CreateCurationRule(rule); err != nil {
+		return err
+	}
+	return s.reload()
+}
+
+func (s *Service) Update(rule *storage.CurationRule) error {
+	if err := validate(rule); err != nil {
+		return err
+	}
+	if err := s.db.UpdateCurationRule(rule); err != nil {
+		return err
+	}
+	return s.reload()
+}
+
+func (s *Service) Delete(id uint) error {
+	if err := s.db.DeleteCurationRule(id); err != nil {
+		return err
+	}
+	return s.reload()
+}
+
+// validate normalizes the pattern and checks pins and hidden documents.
+func validate(rule *storage.CurationRule) error {
+	rule.Pattern = search.NormalizeQuery(rule.Pattern)
+	if rule.Pattern == "" {
+		return fmt.Errorf("%w: pattern is required", ErrInvalidRule)
+	}
+	if rule.Match == "" {
+		rule.Match = storage.MatchExact
+	}
+	switch rule.Match {
+	case storage.MatchExact, storage.MatchPrefix, storage.MatchContains:
+	default:
+		return fmt.Errorf("%w: unknown match type %q", ErrInvalidRule, rule.Match)
+	}
+	if len(rule.Pins) == 0 && len(rule.Hidden) == 0 {
+		return fmt.Errorf("%w: rule pins and hides nothing", ErrInvalidRule)
+	}
+
+	pinned := make(map[string]bool)
+	positions := make(map[int]bool)
+	for _, p := range rule.Pins {
+		if p.DocID == "" || p.Position < 1 {
+			return fmt.Errorf("%w: pin needs a doc_id and a position >= 1", ErrInvalidRule)
+		}
+		if pinned[p.DocID] || positions[p.Position] {
+			return fmt.Errorf("%w: duplicate pin for %q at %d", ErrInvalidRule, p.DocID, p.Position)
+		}
+		pinned[p.DocID], positions[p.Position] = true, true
+	}
+	for _, id := range rule.Hidden {
+		if pinned[id] {
+			return fmt.Errorf("%w: %q is both pinned and hidden", ErrInvalidRule, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package curation
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"search-engine-backend/internal/search"
+	"search-engine-backend/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) *Service {
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	s, err := NewService(db)
+	require.NoError(t, err)
+	return s
+}
+
+func TestLookupPriority(t *testing.T) {
+	s := newTestService(t)
+	changes := 0
+	s.OnChange(func() { changes++ })
+
+	require.NoError(t, s.Create(&storage.CurationRule{Pattern: "Go", Match: storage.MatchContains, Hidden: []string{"spam"}}))
+	exact := &storage.CurationRule{Pattern: " Go  Tutorial ", Pins: []storage.Pin{{DocID: "official", Position: 1}}}
+	require.NoError(t, s.Create(exact))
+	assert.Equal(t, "go tutorial", exact.Pattern)
+	assert.Equal(t, storage.MatchExact, exact.Match)
+	assert.Equal(t, 2, changes)
+
+	c, ok := s.Lookup("go tutorial")
+	require.True(t, ok)
+	assert.Equal(t, []search.Pin{{DocID: "official", Position: 1}}, c.Pins)
+
+	c, ok = s.Lookup("learn go fast")
+	require.True(t, ok)
+	assert.Equal(t, []string{"spam"}, c.Hidden)
+
+	_, ok = s.Lookup("rust")
+	assert.False(t, ok)
+
+	require.NoError(t, s.Delete(exact.ID))
+	c, _ = s.Lookup("go tutorial")
+	assert.Empty(t, c.Pins)
+	assert.ErrorIs(t, s.Delete(exact.ID), storage.ErrNotFound)
+}
+
+func TestUpdate(t *testing.T) {
+	s := newTestService(t)
+	rule := &storage.CurationRule{Pattern: "go", Hidden: []string{"a"}}
+	require.NoError(t, s.Create(rule))
+
+	require.NoError(t, s.Update(&storage.CurationRule{ID: rule.ID, Pattern: "golang", Match: storage.MatchPrefix, Hidden: []string{"b"}}))
+	got, err := s.Get(rule.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "golang", got.Pattern)
+	assert.Equal(t, []string{"b"}, got.Hidden)
+	assert.Len(t, s.List(), 1)
+
+	assert.ErrorIs(t, s.Update(&storage.CurationRule{ID: 99, Pattern: "x", Hidden: []string{"a"}}), storage.ErrNotFound)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []storage.CurationRule{
+		{Pattern: " ", Hidden: []string{"a"}},
+		{Pattern: "go", Match: "regex", Hidden: []string{"a"}},
+		{Pattern: "go"},
+		{Pattern: "go", Pins: []storage.Pin{{DocID: "a", Position: 0}}},
+		{Pattern: "go", Pins: []storage.Pin{{DocID: "a", Position: 1}, {DocID: "b", Position: 1}}},
+		{Pattern: "go", Pins: []storage.Pin{{DocID: "a", Position: 1}}, Hidden: []string{"a"}},
+	}
+	for _, rule := range tests {
+		assert.ErrorIs(t, validate(&rule), ErrInvalidRule)
+	}
+}
+
+func TestWatchPicksUpOtherInstances(t *testing.T) {
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	a, err := NewService(db)
+	require.NoError(t, err)
+	b, err := NewService(db)
+	require.NoError(t, err)
+	var changes atomic.Int32
+	b.OnChange(func() { changes.Add(1) })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b.Watch(ctx, 10*time.Millisecond)
+
+	// 另一个实例新增的规则在下一次轮询时生效，并触发缓存失效回调
+	require.NoError(t, a.Create(&storage.CurationRule{Pattern: "go", Hidden: []string{"spam"}}))
+	assert.Eventually(t, func() bool {
+		_, ok := b.Lookup("go")
+		return ok && changes.Load() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), changes.Load(), "unchanged rules are not reloaded")
+}
@@ -0,0 +1,157 @@
+package filter
+
+import (
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Keyword 敏感词及其分类
+type Keyword struct {
+	Term     string
+	Category string
+	// rule 来自数据库规则时为规则标识，用于审计
+	rule string
+}
+
+// fold makes matching case- and width-insensitive. NFKC turns full-width
+// ASCII into half-width and half-width katakana (including separate voiced
+// marks) into full-width; runes are then lowercased while scanning.
+func fold(text string) string {
+	return norm.NFKC.String(text)
+}
+
+type acNode struct {
+	next map[rune]int32
+	fail int32
+	// out 以该节点结尾的关键词下标；dict 为失败链上下一个有输出的节点，-1 表示没有
+	out  []int32
+	dict int32
+}
+
+// automaton is an Aho-Corasick automaton over folded runes. It is
+// immutable once built and safe for concurrent use.
+type automaton struct {
+	nodes    []acNode
+	keywords []Keyword
+}
+
+func newAutomaton(keywords []Keyword) *automaton {
+	a := &automaton{nodes: []acNode{{next: map[rune]int32{}, dict: -1}}}
+	for _, kw := range keywords {
+		node := int32(0)
+		n := 0
+		for _, r := range fold(kw.Term) {
+			r = unicode.ToLower(r)
+			next, ok := a.nodes[node].next[r]
+			if !ok {
+				next = int32(len(a.nodes))
+				a.nodes = append(a.nodes, acNode{next: map[rune]int32{}, dict: -1})
+				a.nodes[node].next[r] = next
+			}
+			node = next
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		a.nodes[node].out = append(a.nodes[node].out, int32(len(a.keywords)))
+		a.keywords = append(a.keywords, kw)
+	}
+
+	// 按层序计算失败指针与输出链
+	queue := make([]int32, 0, len(a.nodes))
+	for _, child := range a.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for r, child := range a.nodes[node].next {
+			f := a.nodes[node].fail
+			for f != 0 {
+				if _, ok := a.nodes[f].next[r]; ok {
+					break
+				}
+				f = a.nodes[f].fail
+			}
+			if target, ok := a.nodes[f].next[r]; ok && target != child {
+				a.nodes[child].fail = target
+			}
+			fail := a.nodes[child].fail
+			if len(a.nodes[fail].out) > 0 {
+				a.nodes[child].dict = fail
+			} else {
+				a.nodes[child].dict = a.nodes[fail].dict
+			}
+			queue = append(queue, child)
+		}
+	}
+	return a
+}
+
+// scan calls fn for every keyword occurrence in text until fn returns false.
+func (a *automaton) scan(text string, fn func(Keyword) bool) {
+	if a == nil || len(a.keywords) == 0 {
+		return
+	}
+	node := int32(0)
+	for _, r := range fold(text) {
+		r = unicode.ToLower(r)
+		for {
+			if next, ok := a.nodes[node].next[r]; ok {
+				node = next
+				break
+			}
+			if node == 0 {
+				break
+			}
+			node = a.nodes[node].fail
+		}
+		for n := node; n > 0; n = a.nodes[n].dict {
+			for _, i := range a.nodes[n].out {
+				if !fn(a.keywords[i]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Contains reports whether any keyword occurs in text.
+func (a *automaton) Contains(text string) bool {
+	found := false
+	a.scan(text, func(Keyword) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+// find returns the first keyword in text whose category satisfies match.
+func (a *automaton) find(text string, match func(category string) bool) (Keyword, bool) {
+	var found Keyword
+	ok := false
+	a.scan(text, func(kw Keyword) bool {
+		if match(kw.Category) {
+			found, ok = kw, true
+		}
+		return !ok
+	})
+	return found, ok
+}
+
+// Categories returns the distinct categories of keywords found in text,
+// in order of first occurrence.
+func (a *automaton) Categories(text string) []string {
+	var categories []string
+	seen := make(map[string]bool)
+	a.scan(text, func(kw Keyword) bool {
+		if !seen[kw.Category] {
+			seen[kw.Category] = true
+			categories = append(categories, kw.Category)
+		}
+		return true
+	})
+	return categories
+}
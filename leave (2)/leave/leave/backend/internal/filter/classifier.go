@@ -0,0 +1,255 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SafeLabel 训练数据中正常内容的标签
+const SafeLabel = "safe"
+
+// evidenceTokens 参与计算后验概率的最大有效词数，更长的文档按比例缩放似然，
+// 避免后验概率在长文档上饱和为 0 或 1
+const evidenceTokens = 20
+
+// Example 一条标注样本
+type Example struct {
+	Text  string `json:"text"`
+	Label string `json:"label"`
+}
+
+// Classifier is a multinomial naive Bayes text classifier. Its zero value
+// is not usable; train one with TrainClassifier or load a saved model.
+type Classifier struct {
+	Classes []string `json:"classes"`
+	// LogPrior 各类别的先验对数概率
+	LogPrior map[string]float64 `json:"log_prior"`
+	// LogLikelihood 各类别下词的对数概率 (已平滑)；Unseen 为未出现词的对数概率
+	LogLikelihood map[string]map[string]float64 `json:"log_likelihood"`
+	Unseen        map[string]float64            `json:"unseen"`
+
+	// thresholds 各类别的最低置信度，未配置的类别使用 threshold
+	thresholds map[string]float64
+	threshold  float64
+}
+
+// Tokenize segments text for the classifier: runs of letters and digits
+// become lowercase words, and Han text, which has no spaces, becomes
+// character unigrams and bigrams so that words like 成人 and 成人用品 are
+// features without a dictionary.
+func Tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var han []rune
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushHan := func() {
+		for i := range han {
+			tokens = append(tokens, string(han[i]))
+			if i+1 < len(han) {
+				tokens = append(tokens, string(han[i:i+2]))
+			}
+		}
+		han = han[:0]
+	}
+	for _, r := range fold(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return tokens
+}
+
+// TrainClassifier fits a naive Bayes model with additive smoothing alpha
+// (1 is Laplace smoothing). Examples labelled SafeLabel are the negative
+// class; every other label is a category.
+func TrainClassifier(examples []Example, alpha float64) (*Classifier, error) {
+	if alpha <= 0 {
+		return nil, fmt.Errorf("alpha must be positive")
+	}
+	docs := make(map[string]int)
+	counts := make(map[string]map[string]int)
+	totals := make(map[string]int)
+	vocab := make(map[string]bool)
+	for _, ex := range examples {
+		label := strings.TrimSpace(ex.Label)
+		if label == "" {
+			return nil, fmt.Errorf("example without label: %.40q", ex.Text)
+		}
+		docs[label]++
+		if counts[label] == nil {
+			counts[label] = make(map[string]int)
+		}
+		for _, tok := range Tokenize(ex.Text) {
+			counts[label][tok]++
+			totals[label]++
+			vocab[tok] = true
+		}
+	}
+	if len(docs) < 2 {
+		return nil, fmt.Errorf("need examples of at least two labels, got %d", len(docs))
+	}
+
+	c := &Classifier{
+		LogPrior:      make(map[string]float64),
+		LogLikelihood: make(map[string]map[string]float64),
+		Unseen:        make(map[string]float64),
+	}
+	v := float64(len(vocab))
+	for label, n := range docs {
+		c.Classes = append(c.Classes, label)
+		c.LogPrior[label] = math.Log(float64(n) / float64(len(examples)))
+		denom := float64(totals[label]) + alpha*v
+		ll := make(map[string]float64, len(counts[label]))
+		for tok, n := range counts[label] {
+			ll[tok] = math.Log((float64(n) + alpha) / denom)
+		}
+		c.LogLikelihood[label] = ll
+		c.Unseen[label] = math.Log(alpha / denom)
+	}
+	sort.Strings(c.Classes)
+	return c, nil
+}
+
+// LoadClassifier reads a model written by Save.
+func LoadClassifier(path string) (*Classifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Classifier
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(c.Classes) < 2 {
+		return nil, fmt.Errorf("parse %s: model has fewer than two classes", path)
+	}
+	return &c, nil
+}
+
+// Save writes the model as JSON.
+func (c *Classifier) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(c)
+}
+
+// SetThresholds sets the minimum confidence for reporting a category:
+// thresholds per category, def for the others.
+func (c *Classifier) SetThresholds(def float64, thresholds map[string]float64) {
+	c.threshold = def
+	c.thresholds = thresholds
+}
+
+// ParseThresholds parses "category=confidence" entries.
+func ParseThresholds(entries []string) (map[string]float64, error) {
+	thresholds := make(map[string]float64, len(entries))
+	for _, e := range entries {
+		category, value, ok := strings.Cut(e, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid threshold %q, want category=confidence", e)
+		}
+		t, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || t < 0 || t > 1 {
+			return nil, fmt.Errorf("invalid threshold %q: confidence must be between 0 and 1", e)
+		}
+		thresholds[strings.TrimSpace(category)] = t
+	}
+	return thresholds, nil
+}
+
+// Predict returns the most probable label of text and its posterior
+// probability. Tokens that no class has seen are skipped: their smoothed
+// probability is highest in the smallest classes and would pull unrelated
+// text towards them. Beyond evidenceTokens known tokens the likelihood is
+// scaled down so that the posterior of a long document does not saturate
+// at 0 or 1 and the Classify thresholds stay meaningful.
+func (c *Classifier) Predict(text string) (string, float64) {
+	var known []string
+	for _, tok := range Tokenize(text) {
+		if c.seen(tok) {
+			known = append(known, tok)
+		}
+	}
+	scores := make([]float64, len(c.Classes))
+	best := 0
+	for i, label := range c.Classes {
+		ll := c.LogLikelihood[label]
+		likelihood := 0.0
+		for _, tok := range known {
+			if p, ok := ll[tok]; ok {
+				likelihood += p
+			} else {
+				likelihood += c.Unseen[label]
+			}
+		}
+		if len(known) > evidenceTokens {
+			likelihood *= evidenceTokens / float64(len(known))
+		}
+		scores[i] = c.LogPrior[label] + likelihood
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	// 后验概率：log-sum-exp 归一化
+	return c.Classes[best], math.Exp(scores[best] - logSumExp(scores))
+}
+
+// seen reports whether any class has tok in its vocabulary.
+func (c *Classifier) seen(tok string) bool {
+	for _, ll := range c.LogLikelihood {
+		if _, ok := ll[tok]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func logSumExp(xs []float64) float64 {
+	max := math.Inf(-1)
+	for _, x := range xs {
+		max = math.Max(max, x)
+	}
+	sum := 0.0
+	for _, x := range xs {
+		sum += math.Exp(x - max)
+	}
+	return max + math.Log(sum)
+}
+
+// Classify returns the category of a document and the confidence, or ""
+// when the document looks safe or the confidence is below the category's
+// threshold. It implements search.Classifier.
+func (c *Classifier) Classify(title, content string) (string, float64) {
+	label, p := c.Predict(title + "\n" + content)
+	if label == SafeLabel {
+		return "", p
+	}
+	threshold, ok := c.thresholds[label]
+	if !ok {
+		threshold = c.threshold
+	}
+	if p < threshold {
+		return "", p
+	}
+	return label, p
+}
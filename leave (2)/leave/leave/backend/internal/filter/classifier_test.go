@@ -0,0 +1,142 @@
+package filter
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"search-engine-backend/internal/search"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"online", "成", "成人", "人", "casino", "2024"}, Tokenize("ＯＮＬＩＮＥ 成人, Casino-2024"))
+	assert.Empty(t, Tokenize(" ,. "))
+}
+
+var trainingExamples = []Example{
+	{Text: "成人视频 免费观看 色情 直播", Label: "adult"},
+	{Text: "成人用品 激情 视频 在线观看", Label: "adult"},
+	{Text: "hot xxx videos free porn", Label: "adult"},
+	{Text: "在线赌场 百家乐 投注 返水", Label: "gambling"},
+	{Text: "online casino bonus poker slots bet", Label: "gambling"},
+	{Text: "体育投注 赔率 下注 赌场", Label: "gambling"},
+	{Text: "成人高考 报名 时间 考试 大纲", Label: "safe"},
+	{Text: "成人 疫苗 接种 医院 门诊 指南", Label: "safe"},
+	{Text: "golang tutorial web server", Label: "safe"},
+	{Text: "天气预报 明天 北京 晴", Label: "safe"},
+	{Text: "医院 儿科 成人 内科 挂号", Label: "safe"},
+}
+
+func TestClassifier(t *testing.T) {
+	c, err := TrainClassifier(trainingExamples, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"adult", "gambling", "safe"}, c.Classes)
+
+	label, p := c.Predict("免费 色情 视频")
+	assert.Equal(t, "adult", label)
+	assert.Greater(t, p, 0.5)
+	label, _ = c.Predict("casino bet bonus")
+	assert.Equal(t, "gambling", label)
+	// 提到"成人"的医疗页面不应判为成人内容
+	label, _ = c.Predict("成人 疫苗 接种 门诊")
+	assert.Equal(t, SafeLabel, label)
+
+	// 未登录词不影响结果，只剩先验
+	label, p = c.Predict("hello world foo bar")
+	assert.Equal(t, SafeLabel, label)
+	assert.InDelta(t, math.Exp(c.LogPrior[SafeLabel]), p, 1e-9)
+	// 长文档的后验概率不会饱和，阈值仍然有效
+	_, p = c.Predict(strings.Repeat("成人 疫苗 接种 门诊 色情 ", 40))
+	assert.Less(t, p, 0.999)
+
+	c.SetThresholds(0.5, map[string]float64{"gambling": 1.01})
+	category, _ := c.Classify("色情 视频", "免费 观看")
+	assert.Equal(t, "adult", category)
+	category, _ = c.Classify("casino", "poker slots bet")
+	assert.Empty(t, category, "below the gambling threshold")
+
+	// 保存后重新加载，预测结果相同
+	var buf bytes.Buffer
+	require.NoError(t, c.Save(&buf))
+	path := filepath.Join(t.TempDir(), "model.json")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	loaded, err := LoadClassifier(path)
+	require.NoError(t, err)
+	l1, p1 := c.Predict("百家乐 投注")
+	l2, p2 := loaded.Predict("百家乐 投注")
+	assert.Equal(t, l1, l2)
+	assert.InDelta(t, p1, p2, 1e-9)
+
+	_, err = TrainClassifier([]Example{{Text: "x", Label: "safe"}}, 1)
+	assert.Error(t, err)
+	_, err = ParseThresholds([]string{"adult=2"})
+	assert.Error(t, err)
+	th, err := ParseThresholds([]string{"adult=0.95", " gambling = 0.8"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"adult": 0.95, "gambling": 0.8}, th)
+}
+
+func TestFilterByClassifiedCategory(t *testing.T) {
+	s, err := NewService("")
+	require.NoError(t, err)
+	doc := search.Document{ID: "1", URL: "https://example.com/", Title: "t", Category: "gambling", CategoryConfidence: 0.97}
+
+	assert.True(t, s.isBlocked(doc, "CN"))
+	assert.False(t, s.isBlocked(doc, "OTHER"))
+	_, n := s.Filter([]search.Document{doc}, Request{Region: "OTHER", SafeSearch: SafeStrict})
+	assert.Equal(t, 1, n)
+	_, n = s.Filter([]search.Document{doc}, Request{Region: "OTHER", SafeSearch: SafeModerate})
+	assert.Equal(t, 0, n)
+}
+
+func TestClassifiedSafeSkipsDefaultKeywords(t *testing.T) {
+	c, err := TrainClassifier(trainingExamples, 1)
+	require.NoError(t, err)
+	s, err := NewService("")
+	require.NoError(t, err)
+	medical := search.Document{ID: "1", URL: "https://hospital.example.cn/", Title: "成人 疫苗 接种 指南", Content: "成人 疫苗 接种 门诊"}
+
+	// 未经分类器判定的文档仍按内置词表屏蔽
+	assert.True(t, s.isBlocked(medical, "CN"))
+
+	// 分类器判为正常内容后不再被内置词"成人"屏蔽
+	medical.Category, medical.CategoryConfidence = c.Classify(medical.Title, medical.Content)
+	medical.Classified = true
+	require.Empty(t, medical.Category)
+	assert.False(t, s.isBlocked(medical, "CN"))
+	_, n := s.Filter([]search.Document{medical}, Request{Region: "OTHER", SafeSearch: SafeStrict})
+	assert.Equal(t, 0, n)
+
+	// 域名黑名单仍然生效
+	blocked := medical
+	blocked.URL = "https://adult.com/"
+	assert.True(t, s.isBlocked(blocked, "CN"))
+}
+
+func TestClassifiedSafeSkipsKeywordFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "adult.txt"), []byte("成人\n色情\n"), 0o644))
+	s, err := NewService(dir)
+	require.NoError(t, err)
+	medical := search.Document{ID: "1", URL: "https://hospital.example.cn/", Title: "成人 疫苗 接种 指南"}
+	req := Request{Region: "OTHER", SafeSearch: SafeModerate}
+
+	// 关键词文件以文件名为分类，中等安全搜索级别下屏蔽 adult
+	_, n := s.Filter([]search.Document{medical}, req)
+	assert.Equal(t, 1, n)
+
+	medical.Classified = true
+	_, n = s.Filter([]search.Document{medical}, req)
+	assert.Equal(t, 0, n, "a safe classifier verdict overrides keyword file matches")
+
+	// 分类器判为成人内容时照常屏蔽
+	medical.Category, medical.CategoryConfidence = "adult", 0.95
+	_, n = s.Filter([]search.Document{medical}, req)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,104 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+var (
+	// ErrInvalidDomain is returned for entries that are not valid host names.
+	ErrInvalidDomain = errors.New("invalid domain")
+	// ErrPublicSuffix is returned for entries such as "com.cn" or
+	// "github.io" that would match every site registered under them.
+	ErrPublicSuffix = errors.New("domain is a public suffix")
+)
+
+// NormalizeDomain lowercases a domain, drops a trailing dot and converts
+// internationalized names to punycode, so "例子.中国" and "xn--fsqu00a.xn--fiqs8s"
+// compare equal.
+func NormalizeDomain(domain string) (string, error) {
+	d := strings.TrimSuffix(strings.TrimSpace(domain), ".")
+	if d == "" {
+		return "", ErrInvalidDomain
+	}
+	ascii, err := idna.Lookup.ToASCII(d)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q: %v", ErrInvalidDomain, domain, err)
+	}
+	return ascii, nil
+}
+
+// hostOf returns the normalized host of a document URL. URLs without a
+// scheme are parsed as http.
+func hostOf(rawURL string) (string, bool) {
+	raw := strings.TrimSpace(rawURL)
+	if raw == "" {
+		return "", false
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return "", false
+	}
+	host := u.Hostname()
+	if net.ParseIP(host) != nil {
+		return host, true
+	}
+	h, err := NormalizeDomain(host)
+	if err != nil {
+		// 不合规的主机名仍按小写精确匹配
+		return strings.ToLower(host), true
+	}
+	return h, true
+}
+
+// domainSet 按"精确或子域名"匹配的域名集合
+type domainSet map[string]bool
+
+// add normalizes and adds a domain. Public suffixes are rejected.
+func (d domainSet) add(domain string) error {
+	n, err := NormalizeDomain(domain)
+	if err != nil {
+		return err
+	}
+	if suffix, _ := publicsuffix.PublicSuffix(n); suffix == n {
+		return fmt.Errorf("%w: %q", ErrPublicSuffix, domain)
+	}
+	d[n] = true
+	return nil
+}
+
+// match reports whether host or one of its parent domains is in the set.
+// Parents stop above the public suffix, so "example.com" matches
+// "a.example.com" but "myexample.com" and "com" never do.
+func (d domainSet) match(host string) bool {
+	_, ok := d.lookup(host)
+	return ok
+}
+
+// lookup is like match and also returns the matching entry.
+func (d domainSet) lookup(host string) (string, bool) {
+	if len(d) == 0 || host == "" {
+		return "", false
+	}
+	suffix, _ := publicsuffix.PublicSuffix(host)
+	for h := host; len(h) > len(suffix); {
+		if d[h] {
+			return h, true
+		}
+		i := strings.IndexByte(h, '.')
+		if i < 0 {
+			return "", false
+		}
+		h = h[i+1:]
+	}
+	return "", false
+}
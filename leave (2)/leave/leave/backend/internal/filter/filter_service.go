@@ -1,23 +1,126 @@
 package filter
 
 import (
-	"strings"
+	"context"
+	"fmt"
+	"log"
 	"sync"
+	"sync/atomic"
+	"time"
+
 	"search-engine-backend/internal/search"
+	"search-engine-backend/internal/storage"
 )
 
+// sensitiveKeywords 未配置关键词文件时使用的默认词表
+var sensitiveKeywords = []string{"成人", "色情", "赌博", "xxx", "porn"}
+
 type Service struct {
-	blockedDomains map[string]bool
-	mu             sync.RWMutex
+	// blockedDomains 屏蔽的域名及其子域名；allowedDomains 中的域名不受屏蔽域名影响
+	blockedDomains domainSet
+	allowedDomains domainSet
+	// blocklists 按来源导入的屏蔽列表 (见 Importer)
+	blocklists map[string]*blocklist
+	mu         sync.RWMutex
+
+	// keywords 关键词自动机，重新加载时整体替换
+	keywords        atomic.Pointer[automaton]
+	keywordsPath    string
+	keywordsVersion string
+
+	// policies 地区过滤策略，决定各地区屏蔽哪些分类的规则
+	policies *PolicyStore
+	// auditor 记录被过滤的结果，可为空
+	auditor Auditor
+	// rules 数据库中的屏蔽规则 (见 RuleStore)，重新加载时整体替换
+	rules atomic.Pointer[ruleSet]
+	// version 关键词、域名、屏蔽列表、规则或策略每次替换后递增
+	version atomic.Uint64
 }
 
-func NewService() *Service {
+// NewService creates the filter. keywordsPath is a keyword file or
+// directory (see LoadKeywords); empty uses the built-in keyword list.
+func NewService(keywordsPath string) (*Service, error) {
 	s := &Service{
-		blockedDomains: make(map[string]bool),
+		blockedDomains: make(domainSet),
+		allowedDomains: make(domainSet),
+		blocklists:     make(map[string]*blocklist),
+		keywordsPath:   keywordsPath,
 	}
+	s.policies, _ = NewPolicyStore("")
 	// 初始化默认黑名单
 	s.loadDefaultBlacklist()
-	return s
+
+	if keywordsPath == "" {
+		defaults := make([]Keyword, 0, len(sensitiveKeywords))
+		for _, term := range sensitiveKeywords {
+			defaults = append(defaults, Keyword{Term: term, Category: DefaultCategory})
+		}
+		s.keywords.Store(newAutomaton(defaults))
+		return s, nil
+	}
+	if err := s.ReloadKeywords(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ReloadKeywords rebuilds the automaton from the keyword files and swaps
+// it in; matching in progress keeps using the previous one.
+func (s *Service) ReloadKeywords() error {
+	version, err := keywordsVersion(s.keywordsPath)
+	if err != nil {
+		return err
+	}
+	keywords, err := LoadKeywords(s.keywordsPath)
+	if err != nil {
+		return err
+	}
+	s.keywords.Store(newAutomaton(keywords))
+	s.version.Add(1)
+	s.mu.Lock()
+	s.keywordsVersion = version
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch polls the keyword files every interval and reloads them when they
+// change. A broken file is logged and the previous keywords are kept.
+func (s *Service) Watch(ctx context.Context, interval time.Duration) {
+	if s.keywordsPath == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				version, err := keywordsVersion(s.keywordsPath)
+				if err != nil {
+					continue
+				}
+				s.mu.RLock()
+				changed := version != s.keywordsVersion
+				s.mu.RUnlock()
+				if !changed {
+					continue
+				}
+				if err := s.ReloadKeywords(); err != nil {
+					log.Printf("failed to reload filter keywords: %v", err)
+					continue
+				}
+				log.Printf("filter keywords reloaded from %s", s.keywordsPath)
+			}
+		}
+	}()
+}
+
+// KeywordCategories returns the categories of the keywords found in text.
+func (s *Service) KeywordCategories(text string) []string {
+	return s.keywords.Load().Categories(text)
 }
 
 func (s *Service) loadDefaultBlacklist() {
@@ -31,24 +134,136 @@ func (s *Service) loadDefaultBlacklist() {
 		// 在实际系统中，这里会从数据库或配置文件加载大量域名
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
 	for _, d := range domains {
-		s.blockedDomains[d] = true
+		if err := s.BlockDomain(d); err != nil {
+			log.Printf("skipping blocked domain: %v", err)
+		}
 	}
 }
 
-// Filter 处理搜索结果，移除违规内容
-func (s *Service) Filter(results []search.Document) ([]search.Document, int) {
+// BlockDomain blocks a domain and all of its subdomains.
+func (s *Service) BlockDomain(domain string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version.Add(1)
+	return s.blockedDomains.add(domain)
+}
+
+// AllowDomain exempts a domain and its subdomains from domain blocks.
+// Keyword checks still apply.
+func (s *Service) AllowDomain(domain string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version.Add(1)
+	return s.allowedDomains.add(domain)
+}
+
+// setBlocklist replaces the domains imported from source and returns the
+// previous list, nil if none.
+func (s *Service) setBlocklist(source string, list *blocklist) *blocklist {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev := s.blocklists[source]
+	s.blocklists[source] = list
+	s.version.Add(1)
+	return prev
+}
+
+// SetPolicies replaces the built-in region policies.
+func (s *Service) SetPolicies(policies *PolicyStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policies = policies
+	s.version.Add(1)
+}
+
+// Version identifies the rules in effect: it changes whenever keywords,
+// domains, blocklists, database rules or region policies change, so that
+// state derived from filtered results can be dropped.
+func (s *Service) Version() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fmt.Sprintf("%d.%d", s.version.Load(), s.policies.Version())
+}
+
+// Active reports whether anything can be hidden in region at SafeSearch
+// level safe.
+func (s *Service) Active(region, safe string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.matcher(region, safe).active()
+}
+
+// Policy returns the filtering policy of region, nil if nothing is
+// filtered there.
+func (s *Service) Policy(region string) *Policy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policies.Lookup(region)
+}
+
+// SafeSearch returns the level in effect in region when the user asks for
+// level: the regional policy's floor applies if it is stricter.
+func (s *Service) SafeSearch(region, level string) string {
+	return stricterSafeSearch(level, s.Policy(region).SafeSearchFloor())
+}
+
+// Request 一次过滤的上下文
+type Request struct {
+	// Query 用户输入的查询词（未经 HTML 转义），审计记录保存其哈希
+	Query  string
+	Region string
+	// SafeSearch 用户选择的安全搜索级别，地区策略的下限会覆盖更低的级别
+	SafeSearch string
+	// NoAudit 不记录本次隐藏的结果，用于补齐分页时重新扫描的、已在其他页记录过的结果
+	NoAudit bool
+}
+
+// Match 结果被过滤的原因
+type Match struct {
+	// Rule 命中的规则，如 "domain:adult.com"、"keyword:porn"、"blocklist:ads:tracker.com"、"rule:12"、"classifier:adult"
+	Rule     string
+	Category string
+}
+
+// Hidden 一条被过滤的结果，交给 Auditor 记录
+type Hidden struct {
+	Request
+	DocID string
+	URL   string
+	Match
+	Time time.Time
+}
+
+// Auditor 记录每一条被过滤的结果，Record 只能短暂阻塞
+type Auditor interface {
+	Record(Hidden)
+}
+
+// SetAuditor makes Filter report every hit it removes to a.
+func (s *Service) SetAuditor(a Auditor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auditor = a
+}
+
+// Filter 处理搜索结果，按地区策略和安全搜索级别移除违规内容。
+// 地区策略是下限，安全搜索只能在其基础上增加屏蔽的分类
+func (s *Service) Filter(results []search.Document, req Request) ([]search.Document, int) {
 	filteredCount := 0
 	var safeResults []search.Document
 
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	m := s.matcher(req.Region, req.SafeSearch)
+	now := time.Now()
 	for _, doc := range results {
-		if s.isBlocked(doc) {
+		if match, blocked := m.match(doc); blocked {
 			filteredCount++
+			if s.auditor != nil && !req.NoAudit {
+				s.auditor.Record(Hidden{Request: req, DocID: doc.ID, URL: doc.URL, Match: match, Time: now})
+			}
 			continue
 		}
 		safeResults = append(safeResults, doc)
@@ -57,22 +272,188 @@ func (s *Service) Filter(results []search.Document) ([]search.Document, int) {
 	return safeResults, filteredCount
 }
 
-func (s *Service) isBlocked(doc search.Document) bool {
-	// 1. 检查域名
-	// 简单的域名包含检查。实际可能需要更严格的解析。
-	for domain := range s.blockedDomains {
-		if strings.Contains(doc.URL, domain) || strings.Contains(doc.Title, domain) {
-			return true
+// IsQueryBlocked 判断查询词本身在 region 地区、安全搜索级别 safe 下是否包含敏感关键词
+func (s *Service) IsQueryBlocked(query, region, safe string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, blocked := s.matcher(region, safe).keyword(query)
+	return blocked
+}
+
+// matcher 某个地区生效的规则：policy 与安全搜索级别 safe 决定内置规则、关键词文件、
+// 导入的屏蔽列表和不限地区的数据库规则中哪些分类生效；scoped 为限定该地区的数据库规则，总是生效
+type matcher struct {
+	s        *Service
+	policy   *Policy
+	safe     string
+	unscoped *ruleScope
+	scoped   []*ruleScope
+}
+
+// matcher must be called with s.mu held.
+func (s *Service) matcher(region, safe string) matcher {
+	rules := s.rules.Load()
+	policy := s.policies.Lookup(region)
+	m := matcher{
+		s:      s,
+		policy: policy,
+		safe:   stricterSafeSearch(safe, policy.SafeSearchFloor()),
+		scoped: rules.scoped(region),
+	}
+	if rules != nil {
+		m.unscoped = rules.unscoped
+	}
+	return m
+}
+
+// blocks reports whether rules of category are enforced.
+func (m matcher) blocks(category string) bool {
+	return m.policy.Blocks(category) || safeSearchBlocks(m.safe, category)
+}
+
+func always(string) bool { return true }
+
+func never(string) bool { return false }
+
+func (m matcher) active() bool {
+	return m.policy != nil || m.safe != SafeOff || len(m.scoped) > 0
+}
+
+// match returns the rule that blocks doc, if any.
+func (m matcher) match(doc search.Document) (Match, bool) {
+	if !m.active() {
+		return Match{}, false
+	}
+
+	// 1. 检查域名：解析 URL 的主机名，按域名及其子域名匹配，白名单优先
+	host, hasHost := hostOf(doc.URL)
+	allowed := hasHost && m.allowed(host)
+	if hasHost && !allowed {
+		if match, blocked := m.domain(host); blocked {
+			return match, true
 		}
 	}
 
-	// 2. 简单的关键词检查 (实际项目中关键词过滤通常更复杂，涉及 AC 自动机等算法)
-	sensitiveKeywords := []string{"成人", "色情", "赌博", "xxx", "porn"}
-	for _, kw := range sensitiveKeywords {
-		if strings.Contains(doc.Title, kw) || strings.Contains(doc.Content, kw) {
+	// 2. 索引时分类器判定的内容分类
+	if doc.Category != "" && m.blocks(doc.Category) {
+		return Match{Rule: "classifier:" + doc.Category, Category: doc.Category}, true
+	}
+
+	// 3. 关键词检查：AC 自动机一次扫描匹配全部关键词。分类器已判定为正常内容时
+	// 不再按内置词表和关键词文件屏蔽，避免"成人疫苗"这类医疗、教育页面被误伤；
+	// 运营在数据库中添加的关键词规则仍然生效
+	builtin := m.blocks
+	if doc.Classified && doc.Category == "" {
+		builtin = never
+	}
+	if match, blocked := m.keywordWith(doc.Title, builtin); blocked {
+		return match, true
+	}
+	if match, blocked := m.keywordWith(doc.Content, builtin); blocked {
+		return match, true
+	}
+
+	// 4. 逐条求值的规则；通配符域名同样受白名单约束
+	return m.pattern(doc, allowed)
+}
+
+// allowed reports whether host is on any allowlist in effect.
+func (m matcher) allowed(host string) bool {
+	if m.s.allowedDomains.match(host) {
+		return true
+	}
+	for _, list := range m.s.blocklists {
+		if list.allowed.match(host) {
+			return true
+		}
+	}
+	for _, sc := range m.scopes() {
+		if sc.allowed.match(host) {
 			return true
 		}
 	}
-
 	return false
 }
+
+// scopes returns the database rule scopes in effect, unscoped first.
+func (m matcher) scopes() []*ruleScope {
+	var scopes []*ruleScope
+	if m.unscoped != nil {
+		scopes = append(scopes, m.unscoped)
+	}
+	return append(scopes, m.scoped...)
+}
+
+// domain returns the domain rule that blocks host. Allowlists are checked
+// by the caller.
+func (m matcher) domain(host string) (Match, bool) {
+	s := m.s
+	if m.blocks(DefaultCategory) {
+		if d, ok := s.blockedDomains.lookup(host); ok {
+			return Match{Rule: "domain:" + d, Category: DefaultCategory}, true
+		}
+	}
+	// 导入的屏蔽列表以来源名作为分类
+	for source, list := range s.blocklists {
+		if m.blocks(source) {
+			if d, ok := list.blocked.lookup(host); ok {
+				return Match{Rule: "blocklist:" + source + ":" + d, Category: source}, true
+			}
+		}
+	}
+	for _, sc := range m.scopes() {
+		for category, set := range sc.blocked {
+			if sc != m.unscoped || m.blocks(category) {
+				if d, ok := set.lookup(host); ok {
+					return Match{Rule: sc.domainRules[category+"/"+d], Category: category}, true
+				}
+			}
+		}
+	}
+	return Match{}, false
+}
+
+// pattern returns the first pattern rule that matches doc. Wildcard
+// domain rules are skipped when the host is allowlisted.
+func (m matcher) pattern(doc search.Document, allowed bool) (Match, bool) {
+	for _, sc := range m.scopes() {
+		for _, r := range sc.patterns {
+			if sc == m.unscoped && !m.blocks(r.Rule.Category) {
+				continue
+			}
+			if allowed && r.Rule.Kind == storage.FilterRuleWildcard {
+				continue
+			}
+			if r.Match(doc) {
+				return Match{Rule: ruleName(r.Rule.ID), Category: r.Rule.Category}, true
+			}
+		}
+	}
+	return Match{}, false
+}
+
+func (m matcher) keyword(text string) (Match, bool) {
+	return m.keywordWith(text, m.blocks)
+}
+
+// keywordWith is keyword with builtin deciding which categories of the
+// built-in list and the keyword files apply.
+func (m matcher) keywordWith(text string, builtin func(category string) bool) (Match, bool) {
+	if !m.active() {
+		return Match{}, false
+	}
+	if kw, ok := m.s.keywords.Load().find(text, builtin); ok {
+		return Match{Rule: "keyword:" + kw.Term, Category: kw.Category}, true
+	}
+	if m.unscoped != nil {
+		if kw, ok := m.unscoped.keywords.find(text, m.blocks); ok {
+			return Match{Rule: kw.rule, Category: kw.Category}, true
+		}
+	}
+	for _, sc := range m.scoped {
+		if kw, ok := sc.keywords.find(text, always); ok {
+			return Match{Rule: kw.rule, Category: kw.Category}, true
+		}
+	}
+	return Match{}, false
+}
@@ -0,0 +1,189 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"search-engine-backend/internal/search"
+	"search-engine-backend/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// isBlocked reports whether doc is hidden in region with SafeSearch off.
+func (s *Service) isBlocked(doc search.Document, region string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, blocked := s.matcher(region, SafeOff).match(doc)
+	return blocked
+}
+
+func TestAutomaton(t *testing.T) {
+	a := newAutomaton([]Keyword{
+		{Term: "he", Category: "a"},
+		{Term: "she", Category: "b"},
+		{Term: "hers", Category: "c"},
+		{Term: "赌博", Category: "gambling"},
+		{Term: "", Category: "empty"},
+	})
+
+	assert.Equal(t, []string{"b", "a", "c"}, a.Categories("ushers"), "overlapping matches via failure links")
+	assert.True(t, a.Contains("网上赌博平台"))
+	assert.False(t, a.Contains("赌 博"))
+	assert.False(t, a.Contains(""))
+
+	// 大小写与全角/半角不敏感
+	assert.True(t, a.Contains("ＳＨＥ said"))
+	assert.True(t, a.Contains("HeRs"))
+	kana := newAutomaton([]Keyword{{Term: "カジノ", Category: "gambling"}})
+	assert.True(t, kana.Contains("ｶｼﾞﾉ"), "half-width katakana folds to full-width")
+
+	var empty *automaton
+	assert.False(t, empty.Contains("anything"))
+}
+
+func TestLoadKeywords(t *testing.T) {
+	keywords, err := LoadKeywords("../../keywords.example")
+	require.NoError(t, err)
+	assert.Contains(t, keywords, Keyword{Term: "porn", Category: "adult"})
+	assert.Contains(t, keywords, Keyword{Term: "六合彩", Category: "lottery"})
+
+	_, err = parseKeywords(strings.NewReader("ok\n\tcat\n"), "x")
+	assert.Error(t, err)
+
+	kws, err := parseKeywords(strings.NewReader("\ufeffbom\n"), "x")
+	require.NoError(t, err)
+	assert.Equal(t, []Keyword{{Term: "bom", Category: "x"}}, kws)
+}
+
+func TestServiceReloadKeywords(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "adult.txt")
+	require.NoError(t, os.WriteFile(file, []byte("foo\n"), 0o644))
+
+	s, err := NewService(dir)
+	require.NoError(t, err)
+	hits, n := s.Filter([]search.Document{{ID: "1", Title: "FOO bar"}, {ID: "2", Title: "bar"}}, Request{Region: "CN"})
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "2", hits[0].ID)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gambling.txt"), []byte("baz\n"), 0o644))
+	require.NoError(t, s.ReloadKeywords())
+	assert.True(t, s.IsQueryBlocked("ｂａｚ", "CN", SafeOff))
+	assert.Equal(t, []string{"adult", "gambling"}, s.KeywordCategories("foo baz"))
+
+	// 默认词表
+	def, err := NewService("")
+	require.NoError(t, err)
+	assert.True(t, def.IsQueryBlocked("PORN", "CN", SafeOff))
+	assert.False(t, def.IsQueryBlocked("PORN", "OTHER", SafeOff), "built-in rules apply in restricted regions only")
+}
+
+func TestDomainBlocking(t *testing.T) {
+	s, err := NewService("")
+	require.NoError(t, err)
+
+	blocked := func(url string) bool {
+		return s.isBlocked(search.Document{URL: url, Title: "t"}, "CN")
+	}
+	assert.True(t, blocked("https://adult.com/page"))
+	assert.True(t, blocked("https://SUB.Adult.COM./x"))
+	assert.True(t, blocked("adult.com/no-scheme"))
+	assert.False(t, blocked("https://myadult.company.cn/"), "no substring matching")
+	assert.False(t, blocked("https://notadult.com/"))
+	assert.False(t, blocked("https://example.com/?ref=adult.com"), "only the host is matched")
+
+	// IDN 与 punycode 等价
+	require.NoError(t, s.BlockDomain("例子.中国"))
+	assert.True(t, blocked("http://www.xn--fsqu00a.xn--fiqs8s/"))
+	assert.True(t, blocked("http://例子.中国/"))
+
+	// 公共后缀不能作为屏蔽项
+	assert.ErrorIs(t, s.BlockDomain("com.cn"), ErrPublicSuffix)
+	assert.ErrorIs(t, s.BlockDomain("github.io"), ErrPublicSuffix)
+	assert.ErrorIs(t, s.BlockDomain(""), ErrInvalidDomain)
+
+	// 白名单优先于屏蔽域名，但不绕过关键词检查
+	require.NoError(t, s.AllowDomain("safe.adult.com"))
+	assert.True(t, blocked("https://adult.com/"))
+	assert.False(t, blocked("https://safe.adult.com/"))
+	assert.False(t, blocked("https://docs.safe.adult.com/"))
+	assert.True(t, s.isBlocked(search.Document{URL: "https://safe.adult.com/", Title: "porn"}, "CN"))
+}
+
+func TestRuleStore(t *testing.T) {
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	s, err := NewService("")
+	require.NoError(t, err)
+	rules, err := NewRuleStore(db, s)
+	require.NoError(t, err)
+
+	doc := func(url, title string) search.Document {
+		return search.Document{URL: url, Title: title}
+	}
+
+	// 不限地区的规则只在受限地区生效
+	domain := &storage.FilterRule{Kind: storage.FilterRuleDomain, Value: " Casino.Example.COM ", Creator: "alice", Reason: "gambling"}
+	require.NoError(t, rules.Create(domain))
+	assert.Equal(t, "casino.example.com", domain.Value)
+	assert.True(t, s.isBlocked(doc("https://www.casino.example.com/", "t"), "CN"))
+	assert.False(t, s.isBlocked(doc("https://www.casino.example.com/", "t"), "OTHER"))
+
+	// 限定地区的规则只在该地区生效
+	require.NoError(t, rules.Create(&storage.FilterRule{Kind: storage.FilterRuleKeyword, Value: "lottery", Regions: []string{"other"}, Creator: "bob"}))
+	assert.True(t, s.IsQueryBlocked("Lottery results", "OTHER", SafeOff))
+	assert.False(t, s.IsQueryBlocked("Lottery results", "CN", SafeOff))
+
+	// 白名单优先于屏蔽规则
+	allow := &storage.FilterRule{Kind: storage.FilterRuleAllow, Value: "adult.com", Creator: "alice"}
+	require.NoError(t, rules.Create(allow))
+	assert.False(t, s.isBlocked(doc("https://adult.com/", "t"), "CN"))
+	require.NoError(t, rules.Delete(allow.ID))
+	assert.True(t, s.isBlocked(doc("https://adult.com/", "t"), "CN"))
+
+	// 更新保留创建人
+	domain.Value = "poker.example.com"
+	domain.Creator = ""
+	require.NoError(t, rules.Update(domain))
+	got, err := rules.Get(domain.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", got.Creator)
+	assert.False(t, s.isBlocked(doc("https://casino.example.com/", "t"), "CN"))
+	assert.True(t, s.isBlocked(doc("https://poker.example.com/", "t"), "CN"))
+
+	// 过期规则在重新加载后失效
+	soon := time.Now().Add(50 * time.Millisecond)
+	require.NoError(t, rules.Create(&storage.FilterRule{Kind: storage.FilterRuleKeyword, Value: "flash sale", ExpiresAt: &soon, Creator: "carol"}))
+	assert.True(t, s.IsQueryBlocked("flash sale", "CN", SafeOff))
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, rules.Reload())
+	assert.False(t, s.IsQueryBlocked("flash sale", "CN", SafeOff))
+
+	assert.ErrorIs(t, rules.Create(&storage.FilterRule{Kind: storage.FilterRuleDomain, Value: "com.cn", Creator: "alice"}), ErrInvalidRule)
+	assert.ErrorIs(t, rules.Create(&storage.FilterRule{Kind: "regex", Value: "x", Creator: "alice"}), ErrInvalidRule)
+	assert.ErrorIs(t, rules.Create(&storage.FilterRule{Kind: storage.FilterRuleKeyword, Value: "x"}), ErrInvalidRule)
+	past := time.Now().Add(-time.Hour)
+	assert.ErrorIs(t, rules.Create(&storage.FilterRule{Kind: storage.FilterRuleKeyword, Value: "x", ExpiresAt: &past, Creator: "alice"}), ErrInvalidRule)
+	assert.ErrorIs(t, rules.Delete(9999), storage.ErrNotFound)
+
+	all, err := rules.List()
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+}
+
+func TestVersionAndActive(t *testing.T) {
+	s, err := NewService("")
+	require.NoError(t, err)
+	v := s.Version()
+	require.NoError(t, s.BlockDomain("spam.example"))
+	assert.NotEqual(t, v, s.Version(), "rule changes drop state derived from filtered results")
+
+	assert.True(t, s.Active("CN", SafeOff))
+	assert.False(t, s.Active("OTHER", SafeOff))
+	assert.True(t, s.Active("OTHER", SafeModerate))
+}
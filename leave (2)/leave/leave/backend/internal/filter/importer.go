@@ -0,0 +1,357 @@
+package filter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 屏蔽列表格式
+const (
+	FormatHosts   = "hosts"   // hosts 文件：0.0.0.0 example.com
+	FormatAdblock = "adblock" // AdBlock/EasyList 域名规则：||example.com^，@@||example.com^ 为白名单
+	FormatDomains = "domains" // 每行一个域名
+)
+
+// BlocklistSource 一个定期导入的本地屏蔽列表文件
+type BlocklistSource struct {
+	// Name 来源名，同时作为该列表在地区策略中的分类
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Format string `json:"format"`
+}
+
+// ImportResult 一次导入的统计。Added/Removed 相对于该来源上一次导入的结果。
+type ImportResult struct {
+	Source     string    `json:"source"`
+	Blocked    int       `json:"blocked"`
+	Allowed    int       `json:"allowed"`
+	Added      int       `json:"added"`
+	Removed    int       `json:"removed"`
+	Invalid    int       `json:"invalid"`
+	ImportedAt time.Time `json:"imported_at"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// blocklist 解析后的屏蔽列表
+type blocklist struct {
+	blocked domainSet
+	allowed domainSet
+	invalid int
+}
+
+// ParseBlocklist parses a hosts, AdBlock or plain domain list. Lines that
+// are not domain rules (cosmetic filters, rules with options, malformed
+// or public-suffix entries) are counted as invalid.
+func ParseBlocklist(r io.Reader, format string) (blocked, allowed []string, invalid int, err error) {
+	list, err := parseBlocklist(r, format)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	for d := range list.blocked {
+		blocked = append(blocked, d)
+	}
+	for d := range list.allowed {
+		allowed = append(allowed, d)
+	}
+	return blocked, allowed, list.invalid, nil
+}
+
+func parseBlocklist(r io.Reader, format string) (*blocklist, error) {
+	var parse func(line string, list *blocklist) bool
+	switch format {
+	case FormatHosts:
+		parse = parseHostsLine
+	case FormatAdblock:
+		parse = parseAdblockLine
+	case FormatDomains:
+		parse = parseDomainLine
+	default:
+		return nil, fmt.Errorf("unknown blocklist format %q", format)
+	}
+
+	list := &blocklist{blocked: make(domainSet), allowed: make(domainSet)}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	first := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+			first = false
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !parse(line, list) {
+			list.invalid++
+		}
+	}
+	return list, scanner.Err()
+}
+
+// hostsIgnored 为 hosts 文件中的本机条目，不是屏蔽规则
+var hostsIgnored = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"local":                 true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+	"ip6-localnet":          true,
+	"ip6-mcastprefix":       true,
+	"ip6-allnodes":          true,
+	"ip6-allrouters":        true,
+	"ip6-allhosts":          true,
+	"0.0.0.0":               true,
+}
+
+func parseHostsLine(line string, list *blocklist) bool {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+		if line == "" {
+			return true
+		}
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 || net.ParseIP(fields[0]) == nil {
+		return false
+	}
+	ok := true
+	for _, host := range fields[1:] {
+		if hostsIgnored[strings.ToLower(host)] {
+			continue
+		}
+		if list.blocked.add(host) != nil {
+			ok = false
+		}
+	}
+	return ok
+}
+
+func parseAdblockLine(line string, list *blocklist) bool {
+	if strings.HasPrefix(line, "!") || strings.HasPrefix(line, "[") {
+		return true // 注释与 [Adblock Plus 2.0] 头部
+	}
+	set := list.blocked
+	if rest, ok := strings.CutPrefix(line, "@@"); ok {
+		set, line = list.allowed, rest
+	}
+	// 只接受整域名规则 ||example.com^，带选项或路径的规则无法用域名表达
+	rest, ok := strings.CutPrefix(line, "||")
+	if !ok {
+		return false
+	}
+	domain, ok := strings.CutSuffix(rest, "^")
+	if !ok || strings.ContainsAny(domain, "/*$^|") {
+		return false
+	}
+	return set.add(domain) == nil
+}
+
+func parseDomainLine(line string, list *blocklist) bool {
+	if strings.HasPrefix(line, "#") {
+		return true
+	}
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+	if strings.ContainsAny(line, " \t/") {
+		return false
+	}
+	return list.blocked.add(line) == nil
+}
+
+// Importer 定期从本地文件导入屏蔽列表，文件修改后替换该来源的全部域名
+type Importer struct {
+	filter  *Service
+	sources []BlocklistSource
+
+	mu       sync.Mutex
+	versions map[string]string
+	results  map[string]ImportResult
+}
+
+func NewImporter(filter *Service, sources []BlocklistSource) *Importer {
+	return &Importer{
+		filter:   filter,
+		sources:  sources,
+		versions: make(map[string]string),
+		results:  make(map[string]ImportResult),
+	}
+}
+
+// ImportAll imports every source. A failing source keeps its previous
+// domains; its error is recorded in the result.
+func (im *Importer) ImportAll() []ImportResult {
+	results := make([]ImportResult, 0, len(im.sources))
+	for _, src := range im.sources {
+		res, err := im.Import(src)
+		if err != nil {
+			log.Printf("failed to import blocklist %s: %v", src.Name, err)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// Import parses one source and replaces its domains in the filter.
+func (im *Importer) Import(src BlocklistSource) (ImportResult, error) {
+	res := ImportResult{Source: src.Name, ImportedAt: time.Now()}
+	version, list, err := readBlocklist(src)
+	if err != nil {
+		res.Error = err.Error()
+		im.mu.Lock()
+		im.results[src.Name] = res
+		im.mu.Unlock()
+		return res, err
+	}
+
+	prev := im.filter.setBlocklist(src.Name, list)
+	res.Blocked, res.Allowed, res.Invalid = len(list.blocked), len(list.allowed), list.invalid
+	res.Added, res.Removed = diffDomains(prev, list)
+
+	im.mu.Lock()
+	im.versions[src.Name] = version
+	im.results[src.Name] = res
+	im.mu.Unlock()
+	return res, nil
+}
+
+func readBlocklist(src BlocklistSource) (string, *blocklist, error) {
+	f, err := os.Open(src.Path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", nil, err
+	}
+	list, err := parseBlocklist(f, src.Format)
+	if err != nil {
+		return "", nil, err
+	}
+	return fileVersion(info), list, nil
+}
+
+func fileVersion(info os.FileInfo) string {
+	return fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano())
+}
+
+// diffDomains counts the entries of next that are not in prev, and the
+// entries of prev that are not in next.
+func diffDomains(prev, next *blocklist) (added, removed int) {
+	if prev == nil {
+		prev = &blocklist{}
+	}
+	for _, pair := range [][2]domainSet{{prev.blocked, next.blocked}, {prev.allowed, next.allowed}} {
+		for d := range pair[1] {
+			if !pair[0][d] {
+				added++
+			}
+		}
+		for d := range pair[0] {
+			if !pair[1][d] {
+				removed++
+			}
+		}
+	}
+	return added, removed
+}
+
+// Results returns the latest result of each source.
+func (im *Importer) Results() []ImportResult {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	results := make([]ImportResult, 0, len(im.sources))
+	for _, src := range im.sources {
+		if res, ok := im.results[src.Name]; ok {
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+// Run imports every source now, then every interval re-imports the
+// sources whose file changed.
+func (im *Importer) Run(ctx context.Context, interval time.Duration) {
+	if len(im.sources) == 0 {
+		return
+	}
+	for _, res := range im.ImportAll() {
+		logImport(res)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, src := range im.sources {
+					info, err := os.Stat(src.Path)
+					if err != nil {
+						log.Printf("failed to check blocklist %s: %v", src.Name, err)
+						continue
+					}
+					im.mu.Lock()
+					changed := fileVersion(info) != im.versions[src.Name]
+					im.mu.Unlock()
+					if !changed {
+						continue
+					}
+					res, err := im.Import(src)
+					if err != nil {
+						log.Printf("failed to import blocklist %s: %v", src.Name, err)
+						continue
+					}
+					logImport(res)
+				}
+			}
+		}
+	}()
+}
+
+func logImport(res ImportResult) {
+	if res.Error != "" {
+		return
+	}
+	log.Printf("blocklist %s imported: %d blocked, %d allowed, +%d -%d, %d invalid lines",
+		res.Source, res.Blocked, res.Allowed, res.Added, res.Removed, res.Invalid)
+}
+
+// ParseBlocklistSources parses "name=format:path" entries, e.g.
+// "ads=hosts:/etc/blocklists/ads.txt". The name defaults to the path.
+func ParseBlocklistSources(entries []string) ([]BlocklistSource, error) {
+	var sources []BlocklistSource
+	for _, e := range entries {
+		name, spec, hasName := strings.Cut(e, "=")
+		if !hasName {
+			spec = e
+		}
+		format, path, ok := strings.Cut(spec, ":")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("invalid blocklist source %q, want name=format:path", e)
+		}
+		switch format {
+		case FormatHosts, FormatAdblock, FormatDomains:
+		default:
+			return nil, fmt.Errorf("invalid blocklist source %q: unknown format %q", e, format)
+		}
+		if !hasName {
+			name = path
+		}
+		sources = append(sources, BlocklistSource{Name: name, Path: path, Format: format})
+	}
+	return sources, nil
+}
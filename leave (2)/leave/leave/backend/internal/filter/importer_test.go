@@ -0,0 +1,97 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"search-engine-backend/internal/search"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBlocklist(t *testing.T) {
+	hosts := `# StevenBlack hosts
+127.0.0.1 localhost
+::1 localhost ip6-localhost
+0.0.0.0 0.0.0.0
+0.0.0.0 ads.example.com tracker.example.net # inline comment
+0.0.0.0 com.cn
+not-an-ip example.org
+`
+	blocked, allowed, invalid, err := ParseBlocklist(strings.NewReader(hosts), FormatHosts)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"ads.example.com", "tracker.example.net"}, blocked)
+	assert.Empty(t, allowed)
+	assert.Equal(t, 2, invalid)
+
+	adblock := `[Adblock Plus 2.0]
+! Title: EasyList
+||ads.example.com^
+||Tracker.Example.NET^
+@@||good.example.com^
+||example.org^$third-party
+/banner/*
+example.com##.ad
+||example.org/path^
+`
+	blocked, allowed, invalid, err = ParseBlocklist(strings.NewReader(adblock), FormatAdblock)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"ads.example.com", "tracker.example.net"}, blocked)
+	assert.Equal(t, []string{"good.example.com"}, allowed)
+	assert.Equal(t, 4, invalid)
+
+	domains := "\ufeff# list\nads.example.com\n\nbad domain\n例子.中国 # idn\n"
+	blocked, _, invalid, err = ParseBlocklist(strings.NewReader(domains), FormatDomains)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"ads.example.com", "xn--fsqu00a.xn--fiqs8s"}, blocked)
+	assert.Equal(t, 1, invalid)
+
+	_, _, _, err = ParseBlocklist(strings.NewReader(""), "csv")
+	assert.Error(t, err)
+}
+
+func TestImporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	require.NoError(t, os.WriteFile(path, []byte("a.example.com\nb.example.com\n"), 0o644))
+
+	s, err := NewService("")
+	require.NoError(t, err)
+	im := NewImporter(s, []BlocklistSource{{Name: "test", Path: path, Format: FormatDomains}})
+
+	res := im.ImportAll()
+	require.Len(t, res, 1)
+	assert.Equal(t, 2, res[0].Added)
+	assert.True(t, s.isBlocked(search.Document{URL: "https://x.a.example.com/"}, "CN"))
+	assert.False(t, s.isBlocked(search.Document{URL: "https://x.a.example.com/"}, "OTHER"))
+
+	require.NoError(t, os.WriteFile(path, []byte("b.example.com\nc.example.com\nnot valid\n"), 0o644))
+	r, err := im.Import(im.sources[0])
+	require.NoError(t, err)
+	assert.Equal(t, ImportResult{Source: "test", Blocked: 2, Added: 1, Removed: 1, Invalid: 1, ImportedAt: r.ImportedAt}, r)
+	assert.False(t, s.isBlocked(search.Document{URL: "https://a.example.com/"}, "CN"))
+	assert.True(t, s.isBlocked(search.Document{URL: "https://c.example.com/"}, "CN"))
+
+	// 读取失败时保留上一次导入的域名
+	require.NoError(t, os.Remove(path))
+	_, err = im.Import(im.sources[0])
+	assert.Error(t, err)
+	assert.True(t, s.isBlocked(search.Document{URL: "https://c.example.com/"}, "CN"))
+	assert.NotEmpty(t, im.Results()[0].Error)
+}
+
+func TestParseBlocklistSources(t *testing.T) {
+	sources, err := ParseBlocklistSources([]string{"ads=hosts:/etc/ads.txt", "adblock:/tmp/easylist.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, []BlocklistSource{
+		{Name: "ads", Path: "/etc/ads.txt", Format: FormatHosts},
+		{Name: "/tmp/easylist.txt", Path: "/tmp/easylist.txt", Format: FormatAdblock},
+	}, sources)
+
+	_, err = ParseBlocklistSources([]string{"ads=csv:/x"})
+	assert.Error(t, err)
+	_, err = ParseBlocklistSources([]string{"/x"})
+	assert.Error(t, err)
+}
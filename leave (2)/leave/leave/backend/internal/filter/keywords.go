@@ -0,0 +1,96 @@
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadKeywords reads a keyword file, or every *.txt file of a directory.
+// Files are UTF-8 with one term per line, optionally followed by a tab and
+// a category; terms without a category take the file name as category.
+// Blank lines and lines starting with '#' are ignored.
+func LoadKeywords(path string) ([]Keyword, error) {
+	files, err := keywordFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	var keywords []Keyword
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		category := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		kws, err := parseKeywords(f, category)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		keywords = append(keywords, kws...)
+	}
+	return keywords, nil
+}
+
+func keywordFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	files, err := filepath.Glob(filepath.Join(path, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// keywordsVersion identifies the current content of the keyword files by
+// name, size and modification time.
+func keywordsVersion(path string) (string, error) {
+	files, err := keywordFiles(path)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+func parseKeywords(r io.Reader, category string) ([]Keyword, error) {
+	var keywords []Keyword
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if trimmed := strings.TrimSpace(text); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		kw := Keyword{Term: strings.TrimSpace(text), Category: category}
+		if term, cat, ok := strings.Cut(text, "\t"); ok {
+			kw.Term, kw.Category = strings.TrimSpace(term), strings.TrimSpace(cat)
+		}
+		if kw.Term == "" || kw.Category == "" {
+			return nil, fmt.Errorf("line %d: empty term or category", line)
+		}
+		keywords = append(keywords, kw)
+	}
+	return keywords, scanner.Err()
+}
@@ -0,0 +1,246 @@
+package filter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"search-engine-backend/internal/search"
+	"search-engine-backend/internal/storage"
+)
+
+// 字段限定规则可匹配的文档字段
+const (
+	FieldTitle   = "title"
+	FieldContent = "content"
+	FieldURL     = "url"
+)
+
+// defaultFields 未指定字段的关键词类规则匹配标题和正文
+var defaultFields = []string{FieldTitle, FieldContent}
+
+// CompiledRule 编译后的数据库规则，Match 对单个文档求值
+type CompiledRule struct {
+	Rule  storage.FilterRule
+	match func(doc search.Document) bool
+}
+
+// Match reports whether the rule matches doc, regardless of region and
+// policy. For allow rules it reports whether doc's host is allowlisted.
+func (r *CompiledRule) Match(doc search.Document) bool {
+	return r.match(doc)
+}
+
+// CompileRule builds the evaluation function of rule. The rule is
+// expected to have passed validation.
+func CompileRule(rule storage.FilterRule) (*CompiledRule, error) {
+	var match func(search.Document) bool
+	switch rule.Kind {
+	case storage.FilterRuleDomain, storage.FilterRuleAllow:
+		d := make(domainSet)
+		if err := d.add(rule.Value); err != nil {
+			return nil, err
+		}
+		match = func(doc search.Document) bool {
+			host, ok := hostOf(doc.URL)
+			return ok && d.match(host)
+		}
+	case storage.FilterRuleKeyword:
+		term := foldLower(rule.Value)
+		match = fieldMatcher(rule.Fields, func(text string) bool {
+			return strings.Contains(text, term)
+		})
+	case storage.FilterRuleURLRegex:
+		re, err := regexp.Compile(rule.Value)
+		if err != nil {
+			return nil, err
+		}
+		match = func(doc search.Document) bool {
+			path, ok := pathOf(doc.URL)
+			return ok && re.MatchString(path)
+		}
+	case storage.FilterRuleWildcard:
+		re, err := compileWildcard(rule.Value)
+		if err != nil {
+			return nil, err
+		}
+		match = func(doc search.Document) bool {
+			host, ok := hostOf(doc.URL)
+			return ok && re.MatchString(host)
+		}
+	case storage.FilterRuleNear:
+		a, b, distance, err := parseNear(rule.Value)
+		if err != nil {
+			return nil, err
+		}
+		a, b = foldLower(a), foldLower(b)
+		match = fieldMatcher(rule.Fields, func(text string) bool {
+			return near(text, a, b, distance)
+		})
+	default:
+		return nil, fmt.Errorf("unknown kind %q", rule.Kind)
+	}
+	return &CompiledRule{Rule: rule, match: match}, nil
+}
+
+// patternRule 不能放进域名集合或关键词自动机的规则：URL 正则、通配符域名、
+// 字段限定的关键词和邻近关键词
+func patternRule(rule storage.FilterRule) bool {
+	switch rule.Kind {
+	case storage.FilterRuleURLRegex, storage.FilterRuleWildcard, storage.FilterRuleNear:
+		return true
+	case storage.FilterRuleKeyword:
+		return len(rule.Fields) > 0
+	}
+	return false
+}
+
+// fieldMatcher applies match to the folded, lowercased fields of a document.
+func fieldMatcher(fields []string, match func(text string) bool) func(search.Document) bool {
+	if len(fields) == 0 {
+		fields = defaultFields
+	}
+	return func(doc search.Document) bool {
+		for _, field := range fields {
+			var text string
+			switch field {
+			case FieldTitle:
+				text = doc.Title
+			case FieldContent:
+				text = doc.Content
+			case FieldURL:
+				text = doc.URL
+			}
+			if text != "" && match(foldLower(text)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// foldLower 与关键词自动机一致的归一化：NFKC 折叠后转小写
+func foldLower(text string) string {
+	return strings.Map(unicode.ToLower, fold(text))
+}
+
+// pathOf returns the path of a document URL, "/" when it has none.
+func pathOf(rawURL string) (string, bool) {
+	raw := strings.TrimSpace(rawURL)
+	if raw == "" {
+		return "", false
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	if u.Path == "" {
+		return "/", true
+	}
+	return u.Path, true
+}
+
+var wildcardPattern = regexp.MustCompile(`^[a-z0-9.*-]+$`)
+
+// normalizeWildcard lowercases a wildcard domain such as "*.bet*" and
+// rejects patterns that would match almost every host.
+func normalizeWildcard(pattern string) (string, error) {
+	p := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(pattern)), ".")
+	if !strings.Contains(p, "*") {
+		return "", fmt.Errorf("wildcard %q has no *, use a domain rule", pattern)
+	}
+	if !wildcardPattern.MatchString(p) {
+		return "", fmt.Errorf("wildcard %q may only contain letters, digits, '-', '.' and '*'", pattern)
+	}
+	if len(strings.Trim(p, "*.-")) < 2 {
+		return "", fmt.Errorf("wildcard %q is too broad", pattern)
+	}
+	return p, nil
+}
+
+// compileWildcard turns a wildcard domain into an anchored regexp over the
+// whole host; * matches any run of characters, dots included.
+func compileWildcard(pattern string) (*regexp.Regexp, error) {
+	p, err := normalizeWildcard(pattern)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(p, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.Compile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// maxNearDistance 邻近规则允许的最大字符距离
+const maxNearDistance = 1000
+
+var nearPattern = regexp.MustCompile(`(?i)^(.+?)\s+NEAR/(\d+)\s+(.+)$`)
+
+// parseNear parses "A NEAR/N B": A and B at most N characters apart, in
+// either order.
+func parseNear(value string) (a, b string, distance int, err error) {
+	m := nearPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return "", "", 0, fmt.Errorf("near rule %q must look like \"A NEAR/N B\"", value)
+	}
+	distance, err = strconv.Atoi(m[2])
+	if err != nil || distance > maxNearDistance {
+		return "", "", 0, fmt.Errorf("near rule %q: distance must be at most %d", value, maxNearDistance)
+	}
+	a, b = strings.TrimSpace(m[1]), strings.TrimSpace(m[3])
+	return a, b, distance, nil
+}
+
+// near reports whether a and b both occur in text with at most distance
+// characters between them. All arguments must already be folded.
+func near(text, a, b string, distance int) bool {
+	as, bs := runeOffsets(text, a), runeOffsets(text, b)
+	la, lb := utf8.RuneCountInString(a), utf8.RuneCountInString(b)
+	// 两组位置均已排序：每个位置只需与另一组中紧随其后的位置比较，
+	// 更靠后的位置距离只会更远
+	for i, j := 0, 0; i < len(as) && j < len(bs); {
+		if as[i] <= bs[j] {
+			if bs[j]-(as[i]+la) <= distance {
+				return true
+			}
+			i++
+		} else {
+			if as[i]-(bs[j]+lb) <= distance {
+				return true
+			}
+			j++
+		}
+	}
+	return false
+}
+
+// runeOffsets returns the rune offsets of every occurrence of term in text,
+// in ascending order, counting runes in a single pass over text.
+func runeOffsets(text, term string) []int {
+	if term == "" {
+		return nil
+	}
+	var offsets []int
+	runes, counted := 0, 0
+	for start := 0; start < len(text); {
+		i := strings.Index(text[start:], term)
+		if i < 0 {
+			break
+		}
+		pos := start + i
+		runes += utf8.RuneCountInString(text[counted:pos])
+		counted = pos
+		offsets = append(offsets, runes)
+		_, size := utf8.DecodeRuneInString(text[pos:])
+		start = pos + size
+	}
+	return offsets
+}
@@ -0,0 +1,112 @@
+package filter
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"search-engine-backend/internal/search"
+	"search-engine-backend/internal/storage"
+)
+
+func TestCompileRule(t *testing.T) {
+	compile := func(kind, value string, fields ...string) *CompiledRule {
+		t.Helper()
+		r, err := CompileRule(storage.FilterRule{Kind: kind, Value: value, Fields: fields})
+		require.NoError(t, err)
+		return r
+	}
+	doc := func(url, title, content string) search.Document {
+		return search.Document{URL: url, Title: title, Content: content}
+	}
+
+	path := compile(storage.FilterRuleURLRegex, `/casino/`)
+	assert.True(t, path.Match(doc("https://news.example.com/casino/today", "", "")))
+	assert.False(t, path.Match(doc("https://casino.example.com/", "", "")), "host is not part of the path")
+	assert.False(t, path.Match(doc("https://example.com/?q=/casino/", "", "")), "nor is the query")
+
+	wildcard := compile(storage.FilterRuleWildcard, "*.bet*")
+	assert.True(t, wildcard.Match(doc("https://www.bet365.com/", "", "")))
+	assert.True(t, wildcard.Match(doc("sports.bet.example.org/x", "", "")))
+	assert.False(t, wildcard.Match(doc("https://alphabet.com/", "", "")))
+
+	title := compile(storage.FilterRuleKeyword, "Jackpot", FieldTitle)
+	assert.True(t, title.Match(doc("https://a.com/", "Big ＪＡＣＫＰＯＴ", "")))
+	assert.False(t, title.Match(doc("https://a.com/", "news", "jackpot")))
+	url := compile(storage.FilterRuleKeyword, "casino", FieldURL)
+	assert.True(t, url.Match(doc("https://a.com/Casino-guide", "", "")))
+	assert.False(t, url.Match(doc("https://a.com/", "casino", "casino")))
+
+	near := compile(storage.FilterRuleNear, "赌场 NEAR/5 注册")
+	assert.True(t, near.Match(doc("", "", "澳门赌场，立即注册")))
+	assert.True(t, near.Match(doc("", "", "注册就送，赌场")), "either order")
+	assert.False(t, near.Match(doc("", "", "赌场的历史很长，很多年以后我才去注册账号")))
+	assert.False(t, near.Match(doc("", "赌场", "注册")), "fields are matched separately")
+	far := strings.Repeat("赌场很远", 20) + strings.Repeat("。", 10) + strings.Repeat("注册", 20)
+	assert.False(t, near.Match(doc("", "", far)), "many occurrences, all too far apart")
+	assert.True(t, near.Match(doc("", "", far+"，赌场")), "a later occurrence is close enough")
+
+	for _, r := range []storage.FilterRule{
+		{Kind: storage.FilterRuleURLRegex, Value: "(["},
+		{Kind: storage.FilterRuleWildcard, Value: "bet.com"},
+		{Kind: storage.FilterRuleWildcard, Value: "*.*"},
+		{Kind: storage.FilterRuleNear, Value: "a NEAR b"},
+		{Kind: storage.FilterRuleNear, Value: "a NEAR/5000 b"},
+	} {
+		_, err := CompileRule(r)
+		assert.Error(t, err, r.Value)
+	}
+}
+
+func TestPatternRules(t *testing.T) {
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	s, err := NewService("")
+	require.NoError(t, err)
+	rules, err := NewRuleStore(db, s)
+	require.NoError(t, err)
+
+	wildcard := &storage.FilterRule{Kind: storage.FilterRuleWildcard, Value: " *.BET* ", Category: "gambling", Creator: "alice"}
+	require.NoError(t, rules.Create(wildcard))
+	assert.Equal(t, "*.bet*", wildcard.Value)
+	require.NoError(t, rules.Create(&storage.FilterRule{Kind: storage.FilterRuleKeyword, Value: "slots", Fields: []string{"Title", "title"}, Regions: []string{"US"}, Creator: "bob"}))
+
+	betting := search.Document{URL: "https://www.bet365.com/", Title: "Sports"}
+	assert.True(t, s.isBlocked(betting, "CN"))
+	assert.False(t, s.isBlocked(betting, "DE"), "gambling is not filtered in DE")
+	slots := search.Document{URL: "https://games.example.com/", Title: "Free slots"}
+	assert.True(t, s.isBlocked(slots, "US-UT"))
+	assert.False(t, s.isBlocked(search.Document{URL: "https://games.example.com/", Content: "slots"}, "US"))
+	assert.False(t, s.IsQueryBlocked("slots", "US", SafeOff), "field-scoped rules only match documents")
+
+	// 白名单同样覆盖通配符域名规则
+	require.NoError(t, rules.Create(&storage.FilterRule{Kind: storage.FilterRuleAllow, Value: "bet365.com", Creator: "alice"}))
+	assert.False(t, s.isBlocked(betting, "CN"))
+
+	for _, r := range []*storage.FilterRule{
+		{Kind: storage.FilterRuleDomain, Value: "a.com", Fields: []string{"title"}, Creator: "x"},
+		{Kind: storage.FilterRuleKeyword, Value: "a", Fields: []string{"body"}, Creator: "x"},
+		{Kind: storage.FilterRuleNear, Value: "a b", Creator: "x"},
+	} {
+		assert.ErrorIs(t, rules.Create(r), ErrInvalidRule)
+	}
+
+	// 试运行列出命中的规则及其在该地区是否生效，待测规则不影响实际判定
+	result, err := rules.DryRun(search.Document{URL: "https://sports.bet.example.org/casino/", Title: "Free slots"},
+		Request{Region: "DE", SafeSearch: SafeOff},
+		[]storage.FilterRule{{Kind: storage.FilterRuleURLRegex, Value: "^/casino/", Regions: []string{"de"}}})
+	require.NoError(t, err)
+	require.Len(t, result.Matches, 3)
+	assert.Equal(t, wildcard.ID, result.Matches[0].Rule.ID)
+	assert.False(t, result.Matches[0].Enforced)
+	assert.Equal(t, []string{"US"}, result.Matches[1].Rule.Regions)
+	assert.False(t, result.Matches[1].Enforced)
+	assert.Equal(t, storage.FilterRuleURLRegex, result.Matches[2].Rule.Kind)
+	assert.True(t, result.Matches[2].Enforced)
+	assert.False(t, result.Blocked)
+
+	_, err = rules.DryRun(betting, Request{Region: "CN"}, []storage.FilterRule{{Kind: "regex", Value: "x"}})
+	assert.ErrorIs(t, err, ErrInvalidRule)
+}
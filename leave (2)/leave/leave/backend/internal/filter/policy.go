@@ -0,0 +1,241 @@
+package filter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"search-engine-backend/internal/ip"
+)
+
+// AllCategories 在策略中表示屏蔽全部分类
+const AllCategories = "*"
+
+// DefaultCategory 内置黑名单和内置词表的分类
+const DefaultCategory = "default"
+
+// Policy 某个地区的过滤策略：屏蔽哪些分类的规则，以及向用户展示的说明
+type Policy struct {
+	// Region 国家代码 (如 CN) 或行政区代码 (如 US-CA)；"*" 为未配置地区的默认策略
+	Region string `json:"region"`
+	// Categories 屏蔽的规则分类，"*" 表示全部
+	Categories []string `json:"categories"`
+	// Notice 结果被过滤时展示给用户的说明
+	Notice string `json:"notice"`
+	// LegalReference 过滤依据的法规，可选
+	LegalReference string `json:"legal_reference,omitempty"`
+	// SafeSearch 该地区的最低安全搜索级别，用户不能选择更低的级别
+	SafeSearch string `json:"safe_search,omitempty"`
+
+	all        bool
+	categories map[string]bool
+}
+
+// Blocks reports whether rules of category are enforced. A nil policy
+// blocks nothing.
+func (p *Policy) Blocks(category string) bool {
+	if p == nil {
+		return false
+	}
+	return p.all || p.categories[category]
+}
+
+func (p *Policy) compile() error {
+	p.Region = strings.ToUpper(strings.TrimSpace(p.Region))
+	if p.Region == "" {
+		return fmt.Errorf("region is required")
+	}
+	p.all = false
+	p.categories = make(map[string]bool)
+	for _, c := range p.Categories {
+		c = strings.TrimSpace(c)
+		if c == AllCategories {
+			p.all = true
+		} else if c != "" {
+			p.categories[c] = true
+		}
+	}
+	if p.SafeSearch != "" {
+		level, ok := ParseSafeSearch(p.SafeSearch)
+		if !ok {
+			return fmt.Errorf("policy for %s: unknown safe_search level %q", p.Region, p.SafeSearch)
+		}
+		p.SafeSearch = level
+	}
+	if len(p.categories) == 0 && !p.all && p.SafeSearch == "" {
+		return fmt.Errorf("policy for %s blocks no categories", p.Region)
+	}
+	return nil
+}
+
+// SafeSearchFloor returns the minimum SafeSearch level of the policy.
+func (p *Policy) SafeSearchFloor() string {
+	if p == nil || p.SafeSearch == "" {
+		return SafeOff
+	}
+	return p.SafeSearch
+}
+
+// defaultPolicies 未配置策略文件时的策略：只在中国大陆屏蔽全部分类
+func defaultPolicies() []Policy {
+	return []Policy{{
+		Region:     ip.RegionChinaMainland,
+		Categories: []string{AllCategories},
+		Notice:     "根据相关法律法规和政策，部分搜索结果未予显示。",
+	}}
+}
+
+// policyFile 策略配置文件格式
+type policyFile struct {
+	Policies []Policy `json:"policies"`
+}
+
+// PolicyStore maps regions to policies and reloads them when the file
+// changes.
+type PolicyStore struct {
+	path string
+
+	mu       sync.RWMutex
+	policies map[string]*Policy
+	modTime  time.Time
+	// version 每次重新加载后递增
+	version uint64
+}
+
+// NewPolicyStore loads policies from path. An empty path uses the
+// built-in policy, which filters everything in mainland China only.
+func NewPolicyStore(path string) (*PolicyStore, error) {
+	s := &PolicyStore{path: path}
+	if path == "" {
+		policies, err := indexPolicies(defaultPolicies())
+		if err != nil {
+			return nil, err
+		}
+		s.policies = policies
+		return s, nil
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func indexPolicies(list []Policy) (map[string]*Policy, error) {
+	policies := make(map[string]*Policy, len(list))
+	for i := range list {
+		p := &list[i]
+		if err := p.compile(); err != nil {
+			return nil, fmt.Errorf("policy %d: %w", i, err)
+		}
+		if _, dup := policies[p.Region]; dup {
+			return nil, fmt.Errorf("policy %d: duplicate region %s", i, p.Region)
+		}
+		policies[p.Region] = p
+	}
+	return policies, nil
+}
+
+// Lookup returns the policy for region: an exact match, then the country
+// of a subdivision ("US-CA" falls back to "US"), then the "*" default.
+// It returns nil when nothing is filtered in region.
+func (s *PolicyStore) Lookup(region string) *Policy {
+	region = strings.ToUpper(region)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if p, ok := s.policies[region]; ok {
+		return p
+	}
+	if country, _, ok := strings.Cut(region, "-"); ok {
+		if p, ok := s.policies[country]; ok {
+			return p
+		}
+	}
+	return s.policies[AllCategories]
+}
+
+// Policies returns the loaded policies.
+func (s *PolicyStore) Policies() []Policy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]Policy, 0, len(s.policies))
+	for _, p := range s.policies {
+		list = append(list, *p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Region < list[j].Region })
+	return list
+}
+
+// Watch polls the file every interval and reloads it when its modification
+// time changes. A broken file is logged and the previous policies are kept.
+func (s *PolicyStore) Watch(ctx context.Context, interval time.Duration) {
+	if s.path == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				info, err := os.Stat(s.path)
+				if err != nil {
+					continue
+				}
+				s.mu.RLock()
+				changed := !info.ModTime().Equal(s.modTime)
+				s.mu.RUnlock()
+				if !changed {
+					continue
+				}
+				if err := s.reload(); err != nil {
+					log.Printf("failed to reload filter policies: %v", err)
+					continue
+				}
+				log.Printf("filter policies reloaded from %s", s.path)
+			}
+		}
+	}()
+}
+
+func (s *PolicyStore) reload() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	var f policyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	policies, err := indexPolicies(f.Policies)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", s.path, err)
+	}
+
+	s.mu.Lock()
+	s.policies = policies
+	s.modTime = info.ModTime()
+	s.version++
+	s.mu.Unlock()
+	return nil
+}
+
+// Version changes whenever the policies are reloaded.
+func (s *PolicyStore) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
@@ -0,0 +1,121 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"search-engine-backend/internal/search"
+	"search-engine-backend/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyLookup(t *testing.T) {
+	store, err := NewPolicyStore("../../filter_policies.example.json")
+	require.NoError(t, err)
+
+	assert.Equal(t, "DE", store.Lookup("de").Region)
+	assert.Equal(t, "US-UT", store.Lookup("US-UT").Region)
+	assert.Nil(t, store.Lookup("US-CA"), "no US policy")
+	assert.Equal(t, "CN", store.Lookup("CN-GD").Region, "subdivision falls back to country")
+	assert.Nil(t, store.Lookup("OTHER"))
+	assert.NotEmpty(t, store.Lookup("CN").LegalReference)
+
+	p := store.Lookup("DE")
+	assert.True(t, p.Blocks("adult"))
+	assert.False(t, p.Blocks("gambling"))
+	assert.True(t, store.Lookup("CN").Blocks("anything"))
+	var none *Policy
+	assert.False(t, none.Blocks("adult"))
+
+	path := filepath.Join(t.TempDir(), "policies.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"policies":[{"region":"*","categories":["adult"]},{"region":"FR","categories":[]}]}`), 0o644))
+	_, err = NewPolicyStore(path)
+	assert.Error(t, err, "policy without categories")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"policies":[{"region":"*","categories":["adult"]}]}`), 0o644))
+	store, err = NewPolicyStore(path)
+	require.NoError(t, err)
+	assert.Equal(t, "*", store.Lookup("FR").Region, "default policy")
+}
+
+func TestFilterByPolicy(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "adult.txt"), []byte("porn\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gambling.txt"), []byte("casino\n"), 0o644))
+	s, err := NewService(dir)
+	require.NoError(t, err)
+	policies, err := NewPolicyStore("../../filter_policies.example.json")
+	require.NoError(t, err)
+	s.SetPolicies(policies)
+
+	docs := []search.Document{
+		{ID: "adult", Title: "porn"},
+		{ID: "gambling", Title: "online casino"},
+		{ID: "builtin", URL: "https://adult.com/"},
+		{ID: "ok", Title: "news"},
+	}
+	ids := func(region string) []string {
+		hits, _ := s.Filter(docs, Request{Region: region})
+		var out []string
+		for _, d := range hits {
+			out = append(out, d.ID)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"ok"}, ids("CN"))
+	assert.Equal(t, []string{"gambling", "builtin", "ok"}, ids("DE"), "only adult keywords in DE")
+	assert.Equal(t, []string{"builtin", "ok"}, ids("US-UT"))
+	assert.Equal(t, []string{"adult", "gambling", "builtin", "ok"}, ids("OTHER"))
+
+	// 限定地区的数据库规则不受策略分类限制，并作用于该国家的行政区
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	rules, err := NewRuleStore(db, s)
+	require.NoError(t, err)
+	require.NoError(t, rules.Create(&storage.FilterRule{Kind: storage.FilterRuleKeyword, Value: "news", Category: "politics", Regions: []string{"US"}, Creator: "alice"}))
+	require.NoError(t, rules.Create(&storage.FilterRule{Kind: storage.FilterRuleDomain, Value: "adult.com", Category: "adult", Creator: "alice"}))
+	assert.Equal(t, []string{"gambling", "ok"}, ids("DE"), "unscoped adult domain rule applies under the DE policy")
+	assert.Empty(t, ids("US-UT"))
+	assert.Equal(t, []string{"adult", "gambling", "builtin"}, ids("US-CA"))
+	assert.True(t, s.IsQueryBlocked("breaking news", "US-CA", SafeOff))
+	assert.False(t, s.IsQueryBlocked("breaking news", "CN", SafeOff))
+}
+
+func TestSafeSearch(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "adult.txt"), []byte("porn\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gambling.txt"), []byte("casino\n"), 0o644))
+	s, err := NewService(dir)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "policies.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"policies":[{"region":"KR","categories":["gambling"],"safe_search":"moderate"}]}`), 0o644))
+	policies, err := NewPolicyStore(path)
+	require.NoError(t, err)
+	s.SetPolicies(policies)
+
+	docs := []search.Document{{ID: "adult", Title: "porn"}, {ID: "gambling", Title: "casino"}, {ID: "ok", Title: "news"}}
+	count := func(region, safe string) int {
+		_, n := s.Filter(docs, Request{Region: region, SafeSearch: safe})
+		return n
+	}
+	assert.Equal(t, 0, count("OTHER", SafeOff))
+	assert.Equal(t, 1, count("OTHER", SafeModerate))
+	assert.Equal(t, 2, count("OTHER", SafeStrict))
+	assert.True(t, s.IsQueryBlocked("casino", "OTHER", SafeStrict))
+	assert.False(t, s.IsQueryBlocked("casino", "OTHER", SafeModerate))
+
+	// 地区策略是下限：KR 即使关闭安全搜索也按 moderate 处理
+	assert.Equal(t, SafeModerate, s.SafeSearch("KR", SafeOff))
+	assert.Equal(t, SafeStrict, s.SafeSearch("KR", SafeStrict))
+	assert.Equal(t, SafeOff, s.SafeSearch("OTHER", SafeOff))
+	assert.Equal(t, 2, count("KR", SafeOff))
+
+	level, ok := ParseSafeSearch(" Strict ")
+	assert.True(t, ok)
+	assert.Equal(t, SafeStrict, level)
+	_, ok = ParseSafeSearch("maximum")
+	assert.False(t, ok)
+}
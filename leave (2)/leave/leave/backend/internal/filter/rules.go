@@ -0,0 +1,397 @@
+package filter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"search-engine-backend/internal/search"
+	"search-engine-backend/internal/storage"
+)
+
+// ErrInvalidRule is returned when a blocklist rule fails validation.
+var ErrInvalidRule = errors.New("invalid filter rule")
+
+// ruleScope 同一生效范围内的规则
+type ruleScope struct {
+	// blocked 按分类分组的屏蔽域名；domainRules 为 "分类/域名" 对应的规则标识
+	blocked     map[string]domainSet
+	domainRules map[string]string
+	allowed     domainSet
+	keywords    *automaton
+	// patterns URL 正则、通配符域名、字段限定关键词和邻近关键词，逐条求值
+	patterns []*CompiledRule
+}
+
+// ruleSet 数据库规则编译后的匹配结构。unscoped 为不限地区的规则，按地区策略
+// 中的分类生效；regions 中的规则只在指定地区生效，不受策略限制
+type ruleSet struct {
+	unscoped *ruleScope
+	regions  map[string]*ruleScope
+}
+
+// compileRules builds the rule set from the rules that have not expired
+// at now. It also returns the earliest future expiry, zero if none.
+func compileRules(rules []storage.FilterRule, now time.Time) (*ruleSet, time.Time) {
+	var nextExpiry time.Time
+	keywords := make(map[string][]Keyword)
+	set := &ruleSet{regions: make(map[string]*ruleScope)}
+	scopes := make(map[string]*ruleScope)
+	scope := func(region string) *ruleScope {
+		sc, ok := scopes[region]
+		if !ok {
+			sc = &ruleScope{
+				blocked:     make(map[string]domainSet),
+				domainRules: make(map[string]string),
+				allowed:     make(domainSet),
+			}
+			scopes[region] = sc
+			if region == "" {
+				set.unscoped = sc
+			} else {
+				set.regions[region] = sc
+			}
+		}
+		return sc
+	}
+
+	for _, r := range rules {
+		if r.Expired(now) {
+			continue
+		}
+		if r.ExpiresAt != nil && (nextExpiry.IsZero() || r.ExpiresAt.Before(nextExpiry)) {
+			nextExpiry = *r.ExpiresAt
+		}
+		category := r.Category
+		if category == "" {
+			category = DefaultCategory
+		}
+		regions := r.Regions
+		if len(regions) == 0 {
+			regions = []string{""}
+		}
+		if patternRule(r) {
+			compiled, err := CompileRule(r)
+			if err != nil {
+				log.Printf("skipping filter rule %d: %v", r.ID, err)
+				continue
+			}
+			compiled.Rule.Category = category
+			for _, region := range regions {
+				sc := scope(region)
+				sc.patterns = append(sc.patterns, compiled)
+			}
+			continue
+		}
+		for _, region := range regions {
+			sc := scope(region)
+			var err error
+			switch r.Kind {
+			case storage.FilterRuleDomain:
+				if sc.blocked[category] == nil {
+					sc.blocked[category] = make(domainSet)
+				}
+				if err = sc.blocked[category].add(r.Value); err == nil {
+					sc.domainRules[category+"/"+r.Value] = ruleName(r.ID)
+				}
+			case storage.FilterRuleAllow:
+				err = sc.allowed.add(r.Value)
+			case storage.FilterRuleKeyword:
+				keywords[region] = append(keywords[region], Keyword{Term: r.Value, Category: category, rule: ruleName(r.ID)})
+			}
+			if err != nil {
+				log.Printf("skipping filter rule %d: %v", r.ID, err)
+			}
+		}
+	}
+	for region, kws := range keywords {
+		scopes[region].keywords = newAutomaton(kws)
+	}
+	return set, nextExpiry
+}
+
+// ruleName 数据库规则在审计记录中的标识
+func ruleName(id uint) string {
+	return fmt.Sprintf("rule:%d", id)
+}
+
+// scoped returns the region-scoped rules that apply in region, including
+// those of its country when region is a subdivision.
+func (rs *ruleSet) scoped(region string) []*ruleScope {
+	if rs == nil || region == "" {
+		return nil
+	}
+	var scopes []*ruleScope
+	if sc, ok := rs.regions[region]; ok {
+		scopes = append(scopes, sc)
+	}
+	if country, _, ok := strings.Cut(region, "-"); ok {
+		if sc, ok := rs.regions[country]; ok {
+			scopes = append(scopes, sc)
+		}
+	}
+	return scopes
+}
+
+// RuleStore 管理数据库中的屏蔽规则，变更后立即编译并替换到 Service 中
+type RuleStore struct {
+	db     *storage.DB
+	filter *Service
+
+	mu         sync.Mutex
+	version    string
+	nextExpiry time.Time
+}
+
+// NewRuleStore loads the rules from db into filter.
+func NewRuleStore(db *storage.DB, filter *Service) (*RuleStore, error) {
+	r := &RuleStore{db: db, filter: filter}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload compiles the current rules and swaps them into the filter.
+func (r *RuleStore) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	version, err := r.db.FilterRulesVersion()
+	if err != nil {
+		return err
+	}
+	rules, err := r.db.ListFilterRules()
+	if err != nil {
+		return err
+	}
+	set, nextExpiry := compileRules(rules, time.Now())
+	r.filter.rules.Store(set)
+	r.filter.version.Add(1)
+	r.version = version
+	r.nextExpiry = nextExpiry
+	return nil
+}
+
+// Watch polls the rule table every interval, picking up changes made by
+// other instances and dropping rules as they expire.
+func (r *RuleStore) Watch(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				version, err := r.db.FilterRulesVersion()
+				if err != nil {
+					log.Printf("failed to check filter rules: %v", err)
+					continue
+				}
+				r.mu.Lock()
+				stale := version != r.version || (!r.nextExpiry.IsZero() && !time.Now().Before(r.nextExpiry))
+				r.mu.Unlock()
+				if !stale {
+					continue
+				}
+				if err := r.Reload(); err != nil {
+					log.Printf("failed to reload filter rules: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// List returns every rule, including expired ones.
+func (r *RuleStore) List() ([]storage.FilterRule, error) {
+	return r.db.ListFilterRules()
+}
+
+func (r *RuleStore) Get(id uint) (*storage.FilterRule, error) {
+	return r.db.GetFilterRule(id)
+}
+
+func (r *RuleStore) Create(rule *storage.FilterRule) error {
+	if err := validateRule(rule, time.Now()); err != nil {
+		return err
+	}
+	if rule.Creator == "" {
+		return fmt.Errorf("%w: creator is required", ErrInvalidRule)
+	}
+	if err := r.db.CreateFilterRule(rule); err != nil {
+		return err
+	}
+	return r.Reload()
+}
+
+func (r *RuleStore) Update(rule *storage.FilterRule) error {
+	if err := validateRule(rule, time.Now()); err != nil {
+		return err
+	}
+	if err := r.db.UpdateFilterRule(rule); err != nil {
+		return err
+	}
+	return r.Reload()
+}
+
+func (r *RuleStore) Delete(id uint) error {
+	if err := r.db.DeleteFilterRule(id); err != nil {
+		return err
+	}
+	return r.Reload()
+}
+
+// RuleMatch 试运行时命中文档的一条规则
+type RuleMatch struct {
+	Rule storage.FilterRule `json:"rule"`
+	// Enforced 规则在请求的地区和安全搜索级别下是否生效
+	Enforced bool `json:"enforced"`
+}
+
+// DryRunResult 规则试运行的结果
+type DryRunResult struct {
+	Matches []RuleMatch `json:"matches"`
+	// Blocked 为当前生效的全部规则 (含内置规则、关键词文件和屏蔽列表) 的实际判定，不含待测规则
+	Blocked  bool   `json:"blocked"`
+	Rule     string `json:"rule,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+// DryRun evaluates every unexpired rule, plus the unsaved candidates, against
+// doc and reports which match and whether they would be enforced for a
+// request from region at SafeSearch level safe. Nothing is recorded.
+func (r *RuleStore) DryRun(doc search.Document, req Request, candidates []storage.FilterRule) (*DryRunResult, error) {
+	stored, err := r.db.ListFilterRules()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	rules := make([]storage.FilterRule, 0, len(stored)+len(candidates))
+	for _, rule := range stored {
+		if !rule.Expired(now) {
+			rules = append(rules, rule)
+		}
+	}
+	for _, rule := range candidates {
+		if err := validateRule(&rule, now); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	s := r.filter
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m := s.matcher(req.Region, req.SafeSearch)
+	result := &DryRunResult{Matches: []RuleMatch{}}
+	for _, rule := range rules {
+		compiled, err := CompileRule(rule)
+		if err != nil {
+			log.Printf("skipping filter rule %d in dry run: %v", rule.ID, err)
+			continue
+		}
+		if compiled.Match(doc) {
+			result.Matches = append(result.Matches, RuleMatch{Rule: rule, Enforced: m.enforces(rule, req.Region)})
+		}
+	}
+	if match, blocked := m.match(doc); blocked {
+		result.Blocked, result.Rule, result.Category = true, match.Rule, match.Category
+	}
+	return result, nil
+}
+
+// enforces reports whether a database rule takes effect in region under m.
+func (m matcher) enforces(rule storage.FilterRule, region string) bool {
+	if len(rule.Regions) > 0 {
+		country, _, _ := strings.Cut(region, "-")
+		for _, r := range rule.Regions {
+			if region != "" && (r == region || r == country) {
+				return true
+			}
+		}
+		return false
+	}
+	if rule.Kind == storage.FilterRuleAllow {
+		return m.active()
+	}
+	category := rule.Category
+	if category == "" {
+		category = DefaultCategory
+	}
+	return m.blocks(category)
+}
+
+// validateRule normalizes the value and regions of a rule and checks it.
+func validateRule(rule *storage.FilterRule, now time.Time) error {
+	rule.Value = strings.TrimSpace(rule.Value)
+	rule.Category = strings.TrimSpace(rule.Category)
+	rule.Creator = strings.TrimSpace(rule.Creator)
+	if rule.Value == "" {
+		return fmt.Errorf("%w: value is required", ErrInvalidRule)
+	}
+	switch rule.Kind {
+	case storage.FilterRuleDomain, storage.FilterRuleAllow:
+		d := make(domainSet)
+		if err := d.add(rule.Value); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+		for domain := range d {
+			rule.Value = domain
+		}
+	case storage.FilterRuleWildcard:
+		p, err := normalizeWildcard(rule.Value)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+		rule.Value = p
+	case storage.FilterRuleURLRegex, storage.FilterRuleNear, storage.FilterRuleKeyword:
+		if _, err := CompileRule(*rule); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidRule, rule.Kind)
+	}
+
+	// 只有关键词类规则可以限定字段
+	fields := rule.Fields[:0]
+	seenField := make(map[string]bool)
+	for _, field := range rule.Fields {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" || seenField[field] {
+			continue
+		}
+		switch field {
+		case FieldTitle, FieldContent, FieldURL:
+		default:
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidRule, field)
+		}
+		seenField[field] = true
+		fields = append(fields, field)
+	}
+	rule.Fields = fields
+	if len(fields) > 0 && rule.Kind != storage.FilterRuleKeyword && rule.Kind != storage.FilterRuleNear {
+		return fmt.Errorf("%w: fields only apply to keyword and near rules", ErrInvalidRule)
+	}
+
+	seen := make(map[string]bool)
+	regions := rule.Regions[:0]
+	for _, region := range rule.Regions {
+		region = strings.ToUpper(strings.TrimSpace(region))
+		if region == "" || seen[region] {
+			continue
+		}
+		seen[region] = true
+		regions = append(regions, region)
+	}
+	rule.Regions = regions
+
+	if rule.ExpiresAt != nil && !now.Before(*rule.ExpiresAt) {
+		return fmt.Errorf("%w: expires_at is in the past", ErrInvalidRule)
+	}
+	return nil
+}
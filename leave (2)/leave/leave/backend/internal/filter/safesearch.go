@@ -0,0 +1,48 @@
+package filter
+
+import "strings"
+
+// 安全搜索级别
+const (
+	SafeOff      = "off"
+	SafeModerate = "moderate" // 隐藏露骨内容
+	SafeStrict   = "strict"   // 另外隐藏赌博等边缘内容
+)
+
+// safeSearchCategories 各级别隐藏的规则分类。内置词表和黑名单 (DefaultCategory)
+// 以成人内容为主，按露骨内容处理
+var safeSearchCategories = map[string][]string{
+	SafeModerate: {DefaultCategory, "adult", "porn"},
+	SafeStrict:   {DefaultCategory, "adult", "porn", "gambling", "lottery", "drugs", "violence"},
+}
+
+var safeSearchRank = map[string]int{SafeOff: 0, SafeModerate: 1, SafeStrict: 2}
+
+// ParseSafeSearch returns the normalized level and whether it is valid.
+func ParseSafeSearch(level string) (string, bool) {
+	level = strings.ToLower(strings.TrimSpace(level))
+	_, ok := safeSearchRank[level]
+	return level, ok
+}
+
+// stricterSafeSearch returns the stricter of two levels. Unknown levels
+// count as off.
+func stricterSafeSearch(a, b string) string {
+	if safeSearchRank[b] > safeSearchRank[a] {
+		return b
+	}
+	if _, ok := safeSearchRank[a]; !ok {
+		return SafeOff
+	}
+	return a
+}
+
+// safeSearchBlocks reports whether level hides rules of category.
+func safeSearchBlocks(level, category string) bool {
+	for _, c := range safeSearchCategories[level] {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
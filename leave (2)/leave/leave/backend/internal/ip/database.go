@@ -0,0 +1,58 @@
+package ip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnknownFormat is returned for files that are neither an ip2region
+// xdb nor a MaxMind mmdb database.
+var ErrUnknownFormat = errors.New("unknown ip database format")
+
+// database 一个已打开的 IP 库。文件在加载时整体读入内存，之后原地改写或截断文件
+// 不会影响正在使用的库 (内存映射在文件被截断时访问会触发 SIGBUS)
+type database interface {
+	lookup(ip net.IP) (Location, error)
+	close() error
+}
+
+// mmdbMetadataMarker mmdb 文件末尾元数据段的起始标记
+var mmdbMetadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// openDatabase reads the file at path into memory and opens it as xdb or
+// mmdb, chosen by extension, or by content when the extension is neither.
+func openDatabase(path string) (database, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+	var db database
+	switch ext := strings.ToLower(filepath.Ext(path)); {
+	case ext == ".xdb":
+		db, err = newXDB(data)
+	case ext == ".mmdb" || bytes.Contains(tail(data, 128*1024), mmdbMetadataMarker):
+		db, err = newMMDB(data)
+	default:
+		err = ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return db, nil
+}
+
+// tail returns the last n bytes of data.
+func tail(data []byte, n int) []byte {
+	if len(data) <= n {
+		return data
+	}
+	return data[len(data)-n:]
+}
@@ -1,52 +1,186 @@
 package ip
 
 import (
+	"context"
+	"log"
 	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
+// Service 基于本地 IP 库 (ip2region xdb 或 MaxMind mmdb) 识别客户端所在地区
 type Service struct {
-	// 在实际生产中，这里会持有一个 IP 数据库的引用，例如 ip2region
+	path string
+
+	// mu 保护 db：查询持有读锁，重新加载时在写锁下替换并关闭旧库
+	mu      sync.RWMutex
+	db      database
+	modTime time.Time
+}
+
+// NewService opens the IP database at path. An empty path runs without a
+// database: only loopback and private addresses are recognized.
+func NewService(path string) (*Service, error) {
+	s := &Service{path: path}
+	if path == "" {
+		return s, nil
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
-func NewService() *Service {
-	return &Service{}
+// 地区代码
+const (
+	RegionChinaMainland = "CN"
+	RegionOther         = "OTHER"
+)
+
+// Location IP 库查询结果，库中没有的字段为空
+type Location struct {
+	// CountryCode ISO 3166-1 国家代码，如 CN；ip2region 中无法识别的国家为空
+	CountryCode string `json:"country_code"`
+	// ProvinceCode ISO 3166-2 省级代码后缀，如 UT，只有 mmdb 提供
+	ProvinceCode string `json:"province_code,omitempty"`
+	Country      string `json:"country"`
+	Province     string `json:"province"`
+	City         string `json:"city"`
+	ISP          string `json:"isp"`
+}
+
+// Region 地区代码：国家代码，库中有省级代码时为 "US-UT" 形式
+func (l Location) Region() string {
+	if l.CountryCode == "" {
+		return RegionOther
+	}
+	if l.ProvinceCode != "" {
+		return l.CountryCode + "-" + l.ProvinceCode
+	}
+	return l.CountryCode
 }
 
 // IsChinaMainland checks if the given IP belongs to China Mainland.
-// Note: This is a simplified implementation for demonstration purposes.
-// In a real production environment, you should use a reliable IP database like ip2region or GeoIP.
 func (s *Service) IsChinaMainland(ipStr string) bool {
-	// 处理 IPv6 本地回环
-	if ipStr == "::1" {
-		return true
+	country, _, _ := strings.Cut(s.Region(ipStr), "-")
+	return country == RegionChinaMainland
+}
+
+// Region returns the region code of the given IP, RegionOther when it is
+// not in the database.
+func (s *Service) Region(ipStr string) string {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return RegionOther
 	}
 
+	// 本地回环和私有 IP 视为中国大陆（方便本地测试）
+	if ip.IsLoopback() || ip.IsPrivate() {
+		return RegionChinaMainland
+	}
+
+	loc, ok := s.lookup(ip)
+	if !ok {
+		return RegionOther
+	}
+	return loc.Region()
+}
+
+// Lookup returns the country, province, city and ISP of the given IP.
+func (s *Service) Lookup(ipStr string) (Location, bool) {
 	ip := net.ParseIP(ipStr)
 	if ip == nil {
-		return false
+		return Location{}, false
 	}
+	return s.lookup(ip)
+}
 
-	// 1. 本地回环和私有 IP 视为中国大陆（方便本地测试）
-	if ip.IsLoopback() || isPrivateIP(ip) {
-		return true
+func (s *Service) lookup(ip net.IP) (Location, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.db == nil {
+		return Location{}, false
 	}
+	loc, err := s.db.lookup(ip)
+	if err != nil {
+		log.Printf("ip lookup failed for %s: %v", ip, err)
+		return Location{}, false
+	}
+	return loc, loc != Location{}
+}
 
-	// 2. 模拟：假设 1.x.x.x 到 100.x.x.x 范围内的 IP 是中国 IP (仅作演示)
-	// 在实际项目中，这里应该查询数据库
-	if ip4 := ip.To4(); ip4 != nil {
-		if ip4[0] >= 1 && ip4[0] <= 100 {
-			return true
+// reload opens the database file and swaps it in. The previous database
+// is closed once no lookup is using it.
+func (s *Service) reload() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	db, err := openDatabase(s.path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	prev := s.db
+	s.db = db
+	s.modTime = info.ModTime()
+	s.mu.Unlock()
+
+	if prev != nil {
+		if err := prev.close(); err != nil {
+			log.Printf("failed to close previous ip database: %v", err)
 		}
 	}
+	return nil
+}
 
-	return false
+// Watch polls the database file every interval and reloads it when its
+// modification time changes. A broken file is logged and the previous
+// database is kept. Replace the file by renaming a new one over it, so
+// that a reload never reads a half-written file.
+func (s *Service) Watch(ctx context.Context, interval time.Duration) {
+	if s.path == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				info, err := os.Stat(s.path)
+				if err != nil {
+					continue
+				}
+				s.mu.RLock()
+				changed := !info.ModTime().Equal(s.modTime)
+				s.mu.RUnlock()
+				if !changed {
+					continue
+				}
+				if err := s.reload(); err != nil {
+					log.Printf("failed to reload ip database: %v", err)
+					continue
+				}
+				log.Printf("ip database reloaded from %s", s.path)
+			}
+		}
+	}()
 }
 
-func isPrivateIP(ip net.IP) bool {
-	if ip4 := ip.To4(); ip4 != nil {
-		return ip4[0] == 10 ||
-			(ip4[0] == 172 && ip4[1] >= 16 && ip4[1] <= 31) ||
-			(ip4[0] == 192 && ip4[1] == 168)
+// Close releases the database.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return nil
 	}
-	return false
+	err := s.db.close()
+	s.db = nil
+	return err
 }
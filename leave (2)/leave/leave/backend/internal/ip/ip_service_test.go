@@ -0,0 +1,122 @@
+package ip
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type xdbSegment struct {
+	start, end string
+	region     string
+}
+
+// writeXDB writes a version 2 xdb file. Each segment must stay within one
+// /16 so that it sits in a single vector index bucket.
+func writeXDB(t *testing.T, path string, segments []xdbSegment) {
+	t.Helper()
+	indexStart := xdbHeaderSize + 256*xdbVectorCols*xdbVectorEntrySize
+	buf := make([]byte, indexStart+len(segments)*xdbSegmentSize)
+	binary.LittleEndian.PutUint16(buf, xdbVersion)
+	for i, seg := range segments {
+		start := binary.BigEndian.Uint32(net.ParseIP(seg.start).To4())
+		end := binary.BigEndian.Uint32(net.ParseIP(seg.end).To4())
+		ptr := len(buf)
+		buf = append(buf, seg.region...)
+
+		p := indexStart + i*xdbSegmentSize
+		binary.LittleEndian.PutUint32(buf[p:], start)
+		binary.LittleEndian.PutUint32(buf[p+4:], end)
+		binary.LittleEndian.PutUint16(buf[p+8:], uint16(len(seg.region)))
+		binary.LittleEndian.PutUint32(buf[p+10:], uint32(ptr))
+
+		vector := xdbHeaderSize + int(start>>16)*xdbVectorEntrySize
+		if binary.LittleEndian.Uint32(buf[vector:]) == 0 {
+			binary.LittleEndian.PutUint32(buf[vector:], uint32(p))
+		}
+		binary.LittleEndian.PutUint32(buf[vector+4:], uint32(p))
+	}
+	// 先写临时文件再改名，模拟 IP 库的原子替换
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, buf, 0o644))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func TestXDBLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ip2region.xdb")
+	writeXDB(t, path, []xdbSegment{
+		{"1.0.1.0", "1.0.3.255", "中国|0|福建省|福州市|电信"},
+		{"1.0.4.0", "1.0.7.255", "澳大利亚|0|维多利亚|墨尔本|0"},
+		{"1.36.0.0", "1.36.255.255", "中国|0|香港|0|0"},
+		{"8.8.8.0", "8.8.8.255", "美国|加利福尼亚|Mountain View|Google"},
+	})
+	s, err := NewService(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	loc, ok := s.Lookup("1.0.2.3")
+	require.True(t, ok)
+	assert.Equal(t, Location{CountryCode: "CN", Country: "中国", Province: "福建省", City: "福州市", ISP: "电信"}, loc)
+	assert.True(t, s.IsChinaMainland("1.0.2.3"))
+
+	assert.Equal(t, "AU", s.Region("1.0.5.1"))
+	assert.Equal(t, "HK", s.Region("1.36.1.1"))
+	assert.False(t, s.IsChinaMainland("1.36.1.1"))
+	loc, _ = s.Lookup("8.8.8.8")
+	assert.Equal(t, Location{CountryCode: "US", Country: "美国", Province: "加利福尼亚", City: "Mountain View", ISP: "Google"}, loc)
+
+	// 不在库中的地址，包括以前被当作中国的 1.x-100.x 网段
+	_, ok = s.Lookup("1.0.0.1")
+	assert.False(t, ok)
+	assert.Equal(t, RegionOther, s.Region("42.1.1.1"))
+	assert.Equal(t, RegionOther, s.Region("2001:4860::8888"))
+	assert.Equal(t, RegionOther, s.Region("not an ip"))
+	// 本地和内网地址仍视为中国大陆
+	assert.Equal(t, RegionChinaMainland, s.Region("::1"))
+	assert.Equal(t, RegionChinaMainland, s.Region("192.168.1.10"))
+
+	// 原地改写并截断文件不影响已加载的库，直到重新加载
+	require.NoError(t, os.WriteFile(path, []byte("truncated"), 0o644))
+	assert.Equal(t, "AU", s.Region("1.0.5.1"))
+	assert.Equal(t, "HK", s.Region("1.36.1.1"))
+
+	// 替换文件后重新加载
+	writeXDB(t, path, []xdbSegment{{"42.1.0.0", "42.1.255.255", "日本|0|东京都|东京|0"}})
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, future, future))
+	require.NoError(t, s.reload())
+	assert.Equal(t, "JP", s.Region("42.1.1.1"))
+	assert.Equal(t, RegionOther, s.Region("1.0.2.3"))
+}
+
+func TestOpenDatabase(t *testing.T) {
+	s, err := NewService("")
+	require.NoError(t, err)
+	assert.Equal(t, RegionOther, s.Region("1.0.2.3"))
+	assert.Equal(t, RegionChinaMainland, s.Region("10.0.0.1"))
+
+	dir := t.TempDir()
+	unknown := filepath.Join(dir, "geo.dat")
+	require.NoError(t, os.WriteFile(unknown, []byte("not a database"), 0o644))
+	_, err = NewService(unknown)
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	short := filepath.Join(dir, "short.xdb")
+	require.NoError(t, os.WriteFile(short, make([]byte, 1024), 0o644))
+	_, err = NewService(short)
+	assert.Error(t, err)
+
+	bogus := filepath.Join(dir, "bogus.mmdb")
+	require.NoError(t, os.WriteFile(bogus, []byte("not a database"), 0o644))
+	_, err = NewService(bogus)
+	assert.Error(t, err)
+
+	_, err = NewService(filepath.Join(dir, "missing.xdb"))
+	assert.Error(t, err)
+}
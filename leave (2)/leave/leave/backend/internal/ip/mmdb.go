@@ -0,0 +1,76 @@
+package ip
+
+import (
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// mmdbRecord GeoIP2/GeoLite2 City 与 ISP/ASN 库中用到的字段
+type mmdbRecord struct {
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	ISP                          string `maxminddb:"isp"`
+	Organization                 string `maxminddb:"organization"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+type mmdbDatabase struct {
+	reader *maxminddb.Reader
+}
+
+func newMMDB(data []byte) (*mmdbDatabase, error) {
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return &mmdbDatabase{reader: reader}, nil
+}
+
+func (m *mmdbDatabase) lookup(ip net.IP) (Location, error) {
+	var r mmdbRecord
+	if err := m.reader.Lookup(ip, &r); err != nil {
+		return Location{}, err
+	}
+	loc := Location{
+		CountryCode: r.Country.ISOCode,
+		Country:     localName(r.Country.Names),
+		City:        localName(r.City.Names),
+		ISP:         firstNonEmpty(r.ISP, r.Organization, r.AutonomousSystemOrganization),
+	}
+	if len(r.Subdivisions) > 0 {
+		loc.ProvinceCode = r.Subdivisions[0].ISOCode
+		loc.Province = localName(r.Subdivisions[0].Names)
+	}
+	return loc, nil
+}
+
+func (m *mmdbDatabase) close() error {
+	return m.reader.Close()
+}
+
+// localName 优先使用简体中文名称，其次英文
+func localName(names map[string]string) string {
+	if name := names["zh-CN"]; name != "" {
+		return name
+	}
+	return names["en"]
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
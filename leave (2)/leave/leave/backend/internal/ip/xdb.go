@@ -0,0 +1,171 @@
+package ip
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ip2region xdb (第 2 版，IPv4) 文件布局：
+// 256 字节文件头；256×256 个向量索引 (按 IP 前两个字节定位段索引区间，
+// 每项为起止指针各 4 字节)；段索引每条 14 字节 (起始 IP、结束 IP 各 4 字节，
+// 数据长度 2 字节，数据指针 4 字节)，均为小端序；数据为 "国家|区域|省份|城市|ISP"
+const (
+	xdbHeaderSize      = 256
+	xdbVectorCols      = 256
+	xdbVectorEntrySize = 8
+	xdbSegmentSize     = 14
+	xdbVersion         = 2
+)
+
+var errCorruptXDB = errors.New("corrupt xdb file")
+
+type xdbDatabase struct {
+	data []byte
+}
+
+func newXDB(data []byte) (*xdbDatabase, error) {
+	if len(data) < xdbHeaderSize+256*xdbVectorCols*xdbVectorEntrySize {
+		return nil, errCorruptXDB
+	}
+	if v := binary.LittleEndian.Uint16(data); v != xdbVersion {
+		return nil, fmt.Errorf("unsupported xdb version %d", v)
+	}
+	return &xdbDatabase{data: data}, nil
+}
+
+func (x *xdbDatabase) lookup(ip net.IP) (Location, error) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		// 第 2 版 xdb 只包含 IPv4
+		return Location{}, nil
+	}
+	region, err := x.search(binary.BigEndian.Uint32(ip4))
+	if err != nil || region == "" {
+		return Location{}, err
+	}
+	return parseXDBRegion(region), nil
+}
+
+// search returns the raw region string of ip, "" when no segment covers it.
+func (x *xdbDatabase) search(ip uint32) (string, error) {
+	vector := xdbHeaderSize + (int(ip>>24)*xdbVectorCols+int(ip>>16&0xFF))*xdbVectorEntrySize
+	start := int(binary.LittleEndian.Uint32(x.data[vector:]))
+	end := int(binary.LittleEndian.Uint32(x.data[vector+4:]))
+	if start == 0 && end == 0 {
+		return "", nil
+	}
+	if start < xdbHeaderSize || start > end || end+xdbSegmentSize > len(x.data) {
+		return "", errCorruptXDB
+	}
+
+	lo, hi := 0, (end-start)/xdbSegmentSize
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		p := start + mid*xdbSegmentSize
+		seg := x.data[p : p+xdbSegmentSize]
+		switch {
+		case ip < binary.LittleEndian.Uint32(seg):
+			hi = mid - 1
+		case ip > binary.LittleEndian.Uint32(seg[4:]):
+			lo = mid + 1
+		default:
+			n := int(binary.LittleEndian.Uint16(seg[8:]))
+			ptr := int(binary.LittleEndian.Uint32(seg[10:]))
+			if ptr+n > len(x.data) {
+				return "", errCorruptXDB
+			}
+			return string(x.data[ptr : ptr+n]), nil
+		}
+	}
+	return "", nil
+}
+
+func (x *xdbDatabase) close() error {
+	return nil
+}
+
+// parseXDBRegion parses "国家|区域|省份|城市|ISP", or the newer four-field
+// form without 区域. "0" marks an unknown field.
+func parseXDBRegion(region string) Location {
+	fields := strings.Split(region, "|")
+	if len(fields) == 5 {
+		fields = append(fields[:1], fields[2:]...)
+	}
+	for len(fields) < 4 {
+		fields = append(fields, "")
+	}
+	for i, f := range fields {
+		if f == "0" {
+			fields[i] = ""
+		}
+	}
+	loc := Location{Country: fields[0], Province: fields[1], City: fields[2], ISP: fields[3]}
+	loc.CountryCode = xdbCountryCodes[loc.Country]
+	// ip2region 将港澳台记在"中国"下，按省份区分
+	if loc.CountryCode == RegionChinaMainland {
+		for prefix, code := range xdbChinaSARs {
+			if strings.HasPrefix(loc.Province, prefix) {
+				loc.CountryCode = code
+			}
+		}
+	}
+	return loc
+}
+
+var xdbChinaSARs = map[string]string{"香港": "HK", "澳门": "MO", "台湾": "TW"}
+
+// xdbCountryCodes ip2region 中文国家名到 ISO 3166-1 代码，未收录的国家
+// 不返回代码，按 RegionOther 处理
+var xdbCountryCodes = map[string]string{
+	"中国":    "CN",
+	"香港":    "HK",
+	"澳门":    "MO",
+	"台湾":    "TW",
+	"美国":    "US",
+	"加拿大":   "CA",
+	"墨西哥":   "MX",
+	"巴西":    "BR",
+	"阿根廷":   "AR",
+	"英国":    "GB",
+	"法国":    "FR",
+	"德国":    "DE",
+	"荷兰":    "NL",
+	"比利时":   "BE",
+	"瑞士":    "CH",
+	"意大利":   "IT",
+	"西班牙":   "ES",
+	"葡萄牙":   "PT",
+	"瑞典":    "SE",
+	"挪威":    "NO",
+	"芬兰":    "FI",
+	"丹麦":    "DK",
+	"波兰":    "PL",
+	"爱尔兰":   "IE",
+	"奥地利":   "AT",
+	"俄罗斯":   "RU",
+	"乌克兰":   "UA",
+	"土耳其":   "TR",
+	"以色列":   "IL",
+	"阿联酋":   "AE",
+	"沙特阿拉伯": "SA",
+	"伊朗":    "IR",
+	"印度":    "IN",
+	"巴基斯坦":  "PK",
+	"日本":    "JP",
+	"韩国":    "KR",
+	"朝鲜":    "KP",
+	"蒙古":    "MN",
+	"新加坡":   "SG",
+	"马来西亚":  "MY",
+	"泰国":    "TH",
+	"越南":    "VN",
+	"菲律宾":   "PH",
+	"印度尼西亚": "ID",
+	"澳大利亚":  "AU",
+	"新西兰":   "NZ",
+	"南非":    "ZA",
+	"埃及":    "EG",
+}
@@ -0,0 +1,227 @@
+package querylog
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"search-engine-backend/internal/storage"
+)
+
+const (
+	batchSize     = 100
+	flushInterval = 2 * time.Second
+)
+
+// Entry 一次搜索的记录
+type Entry struct {
+	SearchID      string
+	Query         string
+	ResultCount   int64
+	Latency       time.Duration
+	Page          int
+	Restricted    bool
+	FilteredCount int
+	ClientID      string
+	Experiment    string
+	Arm           string
+	Time          time.Time
+}
+
+// Click 一次结果点击
+type Click struct {
+	SearchID   string
+	DocID      string
+	URL        string
+	Position   int
+	ClientID   string
+	Experiment string
+	Arm        string
+	Time       time.Time
+}
+
+// Service 异步写入查询日志，并提供分析查询
+type Service struct {
+	db      *storage.DB
+	entries chan interface{} // storage.QueryLog 或 storage.ClickLog
+	dropped atomic.Uint64
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+func NewService(db *storage.DB, bufferSize int) *Service {
+	s := &Service{
+		db:      db,
+		entries: make(chan interface{}, bufferSize),
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+// Record queues an entry without blocking; entries are dropped when the
+// buffer is full so logging never slows down search.
+func (s *Service) Record(e Entry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	s.enqueue(storage.QueryLog{
+		SearchID:      e.SearchID,
+		Query:         e.Query,
+		ResultCount:   e.ResultCount,
+		LatencyMs:     e.Latency.Milliseconds(),
+		Page:          e.Page,
+		Restricted:    e.Restricted,
+		FilteredCount: e.FilteredCount,
+		ClientID:      e.ClientID,
+		Experiment:    e.Experiment,
+		Arm:           e.Arm,
+		CreatedAt:     e.Time,
+	})
+}
+
+// RecordClick queues a click the same way as Record.
+func (s *Service) RecordClick(c Click) {
+	if c.Time.IsZero() {
+		c.Time = time.Now()
+	}
+	s.enqueue(storage.ClickLog{
+		SearchID:   c.SearchID,
+		DocID:      c.DocID,
+		URL:        c.URL,
+		Position:   c.Position,
+		ClientID:   c.ClientID,
+		Experiment: c.Experiment,
+		Arm:        c.Arm,
+		CreatedAt:  c.Time,
+	})
+}
+
+func (s *Service) enqueue(row interface{}) {
+	select {
+	case s.entries <- row:
+	default:
+		if s.dropped.Add(1)%1000 == 1 {
+			log.Printf("query log buffer full, %d entries dropped so far", s.dropped.Load())
+		}
+	}
+}
+
+// Close flushes queued entries and stops the writer.
+func (s *Service) Close() {
+	s.once.Do(func() {
+		close(s.entries)
+		s.wg.Wait()
+	})
+}
+
+func (s *Service) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	queries := make([]storage.QueryLog, 0, batchSize)
+	var clicks []storage.ClickLog
+	flush := func() {
+		if err := s.db.SaveQueryLogs(queries); err != nil {
+			log.Printf("failed to save %d query logs: %v", len(queries), err)
+		}
+		if err := s.db.SaveClickLogs(clicks); err != nil {
+			log.Printf("failed to save %d click logs: %v", len(clicks), err)
+		}
+		queries, clicks = queries[:0], clicks[:0]
+	}
+
+	for {
+		select {
+		case row, ok := <-s.entries:
+			if !ok {
+				flush()
+				return
+			}
+			switch r := row.(type) {
+			case storage.QueryLog:
+				queries = append(queries, r)
+			case storage.ClickLog:
+				clicks = append(clicks, r)
+			}
+			if len(queries)+len(clicks) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (s *Service) TopQueries(from, to time.Time, limit int) ([]storage.QueryCount, error) {
+	return s.db.TopQueries(from, to, limit)
+}
+
+func (s *Service) ZeroResultQueries(from, to time.Time, limit int) ([]storage.QueryCount, error) {
+	return s.db.ZeroResultQueries(from, to, limit)
+}
+
+func (s *Service) LatencyPercentiles(from, to time.Time) (map[string]int64, error) {
+	return s.db.LatencyPercentiles(from, to, []float64{50, 90, 95, 99})
+}
+
+func (s *Service) FilterRate(from, to time.Time) (*storage.FilterRate, error) {
+	return s.db.FilterRate(from, to)
+}
+
+func (s *Service) ExperimentReport(experiment string, from, to time.Time) ([]storage.ArmReport, error) {
+	return s.db.ExperimentReport(experiment, from, to)
+}
+
+// NewSearchID returns a random ID that ties clicks to the search that
+// produced the clicked result.
+func NewSearchID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AnonymizeClient derives a stable pseudonymous ID from a client IP so that
+// raw addresses never reach the log.
+func AnonymizeClient(salt, clientIP string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(clientIP))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// LoadOrCreateSalt reads the analytics salt stored at path, generating a
+// random one and saving it on first start so that no fixed default salt
+// can be used to reverse the anonymized client IDs.
+func LoadOrCreateSalt(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if salt := strings.TrimSpace(string(data)); salt != "" {
+			return salt, nil
+		}
+		return "", fmt.Errorf("analytics salt file %s is empty", path)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	salt := hex.EncodeToString(b)
+	if err := os.WriteFile(path, []byte(salt+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("save analytics salt: %w", err)
+	}
+	return salt, nil
+}
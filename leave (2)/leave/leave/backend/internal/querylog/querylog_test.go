@@ -0,0 +1,30 @@
+package querylog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrCreateSalt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "analytics.salt")
+
+	salt, err := LoadOrCreateSalt(path)
+	require.NoError(t, err)
+	assert.Len(t, salt, 64)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// 重启后沿用已保存的密钥，匿名 ID 保持稳定
+	again, err := LoadOrCreateSalt(path)
+	require.NoError(t, err)
+	assert.Equal(t, salt, again)
+
+	other, err := LoadOrCreateSalt(filepath.Join(t.TempDir(), "analytics.salt"))
+	require.NoError(t, err)
+	assert.NotEqual(t, salt, other)
+}
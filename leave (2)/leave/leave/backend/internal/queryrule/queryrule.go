@@ -0,0 +1,280 @@
+// Package queryrule applies rewrite, redirect and refuse rules to a query
+// before it reaches the search engine.
+package queryrule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"search-engine-backend/internal/search"
+)
+
+// 规则动作
+const (
+	ActionRewrite  = "rewrite"
+	ActionRedirect = "redirect"
+	ActionRefuse   = "refuse"
+)
+
+// 匹配方式
+const (
+	MatchExact    = "exact"    // 整个查询相同
+	MatchTerm     = "term"     // 以完整词匹配，适合缩写与别名
+	MatchContains = "contains" // 子串匹配，适合不分词的中文
+)
+
+// Rule 一条查询规则。Pattern 与查询均先归一化（小写、合并空格）再匹配。
+type Rule struct {
+	Name    string `json:"name"`
+	Action  string `json:"action"`
+	Match   string `json:"match"`
+	Pattern string `json:"pattern"`
+	// Replacement 改写后的文本，替换匹配到的部分
+	Replacement string `json:"replacement,omitempty"`
+	// URL 导航类查询直接跳转的地址
+	URL string `json:"url,omitempty"`
+	// Message 拒绝查询时展示的政策说明
+	Message string `json:"message,omitempty"`
+	// Regions 规则生效的地区代码（见 ip.Region*），为空表示所有地区
+	Regions []string `json:"regions,omitempty"`
+}
+
+// Decision 规则执行结果。Action 为空表示照常搜索 Query。
+type Decision struct {
+	Action  string `json:"action,omitempty"`
+	Rule    string `json:"rule,omitempty"`
+	Query   string `json:"query"`
+	URL     string `json:"url,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Rewritten 表示 Query 已被改写
+	Rewritten bool `json:"rewritten,omitempty"`
+}
+
+// file 配置文件格式
+type file struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Store holds the rules and reloads them when the file changes.
+type Store struct {
+	path string
+
+	mu      sync.RWMutex
+	rules   []Rule
+	modTime time.Time
+}
+
+// NewStore loads rules from path. An empty path means no rules.
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	if path == "" {
+		return s, nil
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Apply runs the rules in file order against a query from region.
+// Rewrites accumulate, so a rewritten query can still be redirected or
+// refused by a later rule; redirect and refuse stop evaluation.
+func (s *Store) Apply(query, region string) Decision {
+	s.mu.RLock()
+	rules := s.rules
+	s.mu.RUnlock()
+
+	d := Decision{Query: query}
+	normalized := search.NormalizeQuery(query)
+	for _, r := range rules {
+		if !r.appliesTo(region) {
+			continue
+		}
+		rewritten, ok := r.match(normalized)
+		if !ok {
+			continue
+		}
+		switch r.Action {
+		case ActionRewrite:
+			normalized = rewritten
+			d.Query, d.Rewritten = rewritten, true
+			d.Action, d.Rule = ActionRewrite, r.Name
+		case ActionRedirect:
+			d.Action, d.Rule, d.URL = ActionRedirect, r.Name, r.URL
+			return d
+		case ActionRefuse:
+			d.Action, d.Rule, d.Message = ActionRefuse, r.Name, r.Message
+			return d
+		}
+	}
+	return d
+}
+
+// appliesTo reports whether the rule covers region; a country code also
+// covers its subdivisions ("US" applies to "US-UT").
+func (r Rule) appliesTo(region string) bool {
+	if len(r.Regions) == 0 {
+		return true
+	}
+	country, _, _ := strings.Cut(region, "-")
+	for _, code := range r.Regions {
+		if strings.EqualFold(code, region) || strings.EqualFold(code, country) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether the normalized query matches and returns the query
+// with the matched part replaced by Replacement.
+func (r Rule) match(query string) (string, bool) {
+	switch r.Match {
+	case MatchContains:
+		if !strings.Contains(query, r.Pattern) {
+			return "", false
+		}
+		return search.NormalizeQuery(strings.ReplaceAll(query, r.Pattern, r.Replacement)), true
+	case MatchTerm:
+		terms, pattern := strings.Fields(query), strings.Fields(r.Pattern)
+		var out []string
+		found := false
+		for i := 0; i < len(terms); {
+			if i+len(pattern) <= len(terms) && equalTerms(terms[i:i+len(pattern)], pattern) {
+				out = append(out, r.Replacement)
+				i += len(pattern)
+				found = true
+				continue
+			}
+			out = append(out, terms[i])
+			i++
+		}
+		if !found {
+			return "", false
+		}
+		return search.NormalizeQuery(strings.Join(out, " ")), true
+	default:
+		if query != r.Pattern {
+			return "", false
+		}
+		return search.NormalizeQuery(r.Replacement), true
+	}
+}
+
+func equalTerms(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Rules returns the loaded rules.
+func (s *Store) Rules() []Rule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Rule(nil), s.rules...)
+}
+
+// Watch polls the file every interval and reloads it when its modification
+// time changes. A broken file is logged and the previous rules are kept.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	if s.path == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				info, err := os.Stat(s.path)
+				if err != nil {
+					continue
+				}
+				s.mu.RLock()
+				changed := !info.ModTime().Equal(s.modTime)
+				s.mu.RUnlock()
+				if !changed {
+					continue
+				}
+				if err := s.reload(); err != nil {
+					log.Printf("failed to reload query rules: %v", err)
+					continue
+				}
+				log.Printf("query rules reloaded from %s", s.path)
+			}
+		}
+	}()
+}
+
+func (s *Store) reload() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	for i := range f.Rules {
+		if err := validate(&f.Rules[i]); err != nil {
+			return fmt.Errorf("parse %s: rule %d: %w", s.path, i, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.rules = f.Rules
+	s.modTime = info.ModTime()
+	s.mu.Unlock()
+	return nil
+}
+
+// validate normalizes the pattern and checks the fields each action needs.
+func validate(r *Rule) error {
+	r.Pattern = search.NormalizeQuery(r.Pattern)
+	if r.Pattern == "" {
+		return fmt.Errorf("pattern is required")
+	}
+	if r.Match == "" {
+		r.Match = MatchExact
+	}
+	switch r.Match {
+	case MatchExact, MatchTerm, MatchContains:
+	default:
+		return fmt.Errorf("unknown match type %q", r.Match)
+	}
+
+	switch r.Action {
+	case ActionRewrite:
+		if strings.TrimSpace(r.Replacement) == "" {
+			return fmt.Errorf("rewrite rule needs a replacement")
+		}
+	case ActionRedirect:
+		u, err := url.Parse(r.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("redirect rule needs an absolute http(s) url")
+		}
+	case ActionRefuse:
+		if r.Message == "" {
+			return fmt.Errorf("refuse rule needs a message")
+		}
+	default:
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package queryrule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	s, err := NewStore("../../query_rules.example.json")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		query  string
+		region string
+		want   Decision
+	}{
+		{"no rule", "Go Web", "CN", Decision{Query: "Go Web"}},
+		{"term rewrite", "K8S  tutorial", "OTHER", Decision{Action: ActionRewrite, Rule: "k8s", Query: "kubernetes tutorial", Rewritten: true}},
+		{"term is not substring", "json parser", "OTHER", Decision{Query: "json parser"}},
+		{"chained rewrites", "js k8s", "OTHER", Decision{Action: ActionRewrite, Rule: "js", Query: "javascript kubernetes", Rewritten: true}},
+		{"redirect", "GitHub", "OTHER", Decision{Action: ActionRedirect, Rule: "github", Query: "GitHub", URL: "https://github.com"}},
+		{"refuse in region", "在线赌博网站", "CN", Decision{Action: ActionRefuse, Rule: "gambling-cn", Query: "在线赌博网站", Message: "根据相关法律法规和政策，该查询的结果未予显示。"}},
+		{"subdivision of region", "在线赌博网站", "CN-GD", Decision{Action: ActionRefuse, Rule: "gambling-cn", Query: "在线赌博网站", Message: "根据相关法律法规和政策，该查询的结果未予显示。"}},
+		{"other region", "在线赌博网站", "OTHER", Decision{Query: "在线赌博网站"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.Apply(tt.query, tt.region))
+		})
+	}
+}
+
+func TestEmptyStore(t *testing.T) {
+	s, err := NewStore("")
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Query: "go"}, s.Apply("go", "CN"))
+}
+
+func TestReloadKeepsRulesOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules":[{"action":"redirect","pattern":"go","url":"https://go.dev"}]}`), 0o644))
+	s, err := NewStore(path)
+	require.NoError(t, err)
+
+	for _, bad := range []string{
+		`{"rules":[{"action":"redirect","pattern":"go","url":"javascript:alert(1)"}]}`,
+		`{"rules":[{"action":"rewrite","pattern":"go"}]}`,
+		`{"rules":[{"action":"refuse","pattern":"go","match":"regex","message":"x"}]}`,
+		`{"rules":[{"action":"delete","pattern":"go"}]}`,
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(bad), 0o644))
+		assert.Error(t, s.reload(), bad)
+	}
+	assert.Equal(t, "https://go.dev", s.Apply("go", "CN").URL)
+}
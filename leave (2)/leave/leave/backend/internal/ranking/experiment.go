@@ -0,0 +1,104 @@
+package ranking
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// VariantNoRelaxation 关闭无结果放宽流程的检索管线变体
+const VariantNoRelaxation = "no_relaxation"
+
+var knownVariants = map[string]bool{"": true, VariantNoRelaxation: true}
+
+// Experiment 一个排序实验：按匿名 ID 将用户确定性地分到各实验组
+type Experiment struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Arms    []Arm  `json:"arms"`
+}
+
+// Arm 实验组，Weight 为流量占比（相对值）
+type Arm struct {
+	Name    string `json:"name"`
+	Weight  int    `json:"weight"`
+	Profile string `json:"profile,omitempty"`
+	Variant string `json:"variant,omitempty"`
+}
+
+// Assignment 用户所在的实验组
+type Assignment struct {
+	Experiment string
+	Arm        string
+	Profile    string
+	Variant    string
+}
+
+// assign hashes the experiment name with the anonymous ID so that a user
+// keeps the same arm while independent experiments are not correlated.
+func (e Experiment) assign(anonID string) Arm {
+	total := 0
+	for _, a := range e.Arms {
+		total += a.Weight
+	}
+	h := fnv.New32a()
+	h.Write([]byte(e.Name + ":" + anonID))
+	n := int(h.Sum32() % uint32(total))
+	for _, a := range e.Arms {
+		if n < a.Weight {
+			return a
+		}
+		n -= a.Weight
+	}
+	return e.Arms[len(e.Arms)-1]
+}
+
+func validateExperiments(experiments []Experiment, profiles map[string]Profile) error {
+	enabled := 0
+	for _, e := range experiments {
+		if e.Name == "" {
+			return fmt.Errorf("experiment without name")
+		}
+		if len(e.Arms) == 0 {
+			return fmt.Errorf("experiment %q has no arms", e.Name)
+		}
+		for _, a := range e.Arms {
+			if a.Name == "" || a.Weight <= 0 {
+				return fmt.Errorf("experiment %q: arm needs a name and a positive weight", e.Name)
+			}
+			if _, ok := profiles[a.Profile]; a.Profile != "" && !ok {
+				return fmt.Errorf("experiment %q: arm %q uses unknown profile %q", e.Name, a.Name, a.Profile)
+			}
+			if !knownVariants[a.Variant] {
+				return fmt.Errorf("experiment %q: arm %q uses unknown variant %q", e.Name, a.Name, a.Variant)
+			}
+		}
+		if e.Enabled {
+			enabled++
+		}
+	}
+	// 同一时间只运行一个实验，避免多个实验同时改动排序而互相干扰
+	if enabled > 1 {
+		return fmt.Errorf("%d experiments enabled, at most one may run at a time", enabled)
+	}
+	return nil
+}
+
+// Assign returns the arm of the running experiment for anonID.
+// ok is false when no experiment is enabled.
+func (s *Store) Assign(anonID string) (Assignment, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, e := range s.experiments {
+		if !e.Enabled {
+			continue
+		}
+		arm := e.assign(anonID)
+		return Assignment{
+			Experiment: e.Name,
+			Arm:        arm.Name,
+			Profile:    arm.Profile,
+			Variant:    arm.Variant,
+		}, true
+	}
+	return Assignment{}, false
+}
@@ -0,0 +1,42 @@
+package ranking
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExperimentAssign(t *testing.T) {
+	e := Experiment{
+		Name:    "phrase-boost",
+		Enabled: true,
+		Arms: []Arm{
+			{Name: "control", Weight: 1},
+			{Name: "treatment", Weight: 1, Profile: "fresh"},
+		},
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		id := fmt.Sprintf("user-%d", i)
+		arm := e.assign(id)
+		assert.Equal(t, arm, e.assign(id), "assignment is deterministic")
+		counts[arm.Name]++
+	}
+	assert.InDelta(t, 5000, counts["control"], 300)
+	assert.InDelta(t, 5000, counts["treatment"], 300)
+}
+
+func TestValidateExperiments(t *testing.T) {
+	profiles := map[string]Profile{DefaultName: DefaultProfile()}
+	arms := []Arm{{Name: "a", Weight: 1}}
+
+	assert.NoError(t, validateExperiments([]Experiment{{Name: "x", Enabled: true, Arms: arms}}, profiles))
+	assert.Error(t, validateExperiments([]Experiment{{Name: "x", Arms: []Arm{{Name: "a", Weight: 1, Profile: "missing"}}}}, profiles))
+	assert.Error(t, validateExperiments([]Experiment{{Name: "x", Arms: []Arm{{Name: "a", Weight: 1, Variant: "bogus"}}}}, profiles))
+	assert.Error(t, validateExperiments([]Experiment{
+		{Name: "x", Enabled: true, Arms: arms},
+		{Name: "y", Enabled: true, Arms: arms},
+	}, profiles), "only one experiment may run at a time")
+}
@@ -0,0 +1,219 @@
+package ranking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultName 未配置文件时内置排序方案的名称
+const DefaultName = "default"
+
+// ErrUnknownProfile is returned when a requested profile is not configured.
+var ErrUnknownProfile = errors.New("unknown ranking profile")
+
+// Profile 一套排序参数
+type Profile struct {
+	Name string `json:"name"`
+	// Fields 字段权重，例如 {"title": 3, "content": 1}
+	Fields map[string]float64 `json:"fields"`
+	// PhraseBoost/PhraseSlop 短语邻近加权，PhraseBoost 为 0 时不启用
+	PhraseBoost float64 `json:"phrase_boost,omitempty"`
+	PhraseSlop  int     `json:"phrase_slop,omitempty"`
+	// Freshness 时效衰减，为空时不启用
+	Freshness *Freshness `json:"freshness,omitempty"`
+	// AuthorityWeight 域名权威度权重，为 0 时不启用
+	AuthorityWeight    float64 `json:"authority_weight,omitempty"`
+	MinimumShouldMatch string  `json:"minimum_should_match,omitempty"`
+}
+
+// Freshness 基于 timestamp 字段的高斯衰减
+type Freshness struct {
+	Scale  string  `json:"scale"` // 例如 "30d"
+	Decay  float64 `json:"decay"` // scale 处的得分比例，例如 0.5
+	Weight float64 `json:"weight"`
+}
+
+// DefaultProfile reproduces the original hard-coded boosts.
+func DefaultProfile() Profile {
+	return Profile{
+		Name:   DefaultName,
+		Fields: map[string]float64{"title": 3, "content": 1},
+	}
+}
+
+// FieldList renders the weights in ES "field^boost" syntax.
+func (p Profile) FieldList() []string {
+	fields := make([]string, 0, len(p.Fields))
+	for name, weight := range p.Fields {
+		if weight == 1 {
+			fields = append(fields, name)
+		} else {
+			fields = append(fields, fmt.Sprintf("%s^%g", name, weight))
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// file 配置文件格式
+type file struct {
+	Default     string       `json:"default"`
+	Profiles    []Profile    `json:"profiles"`
+	Experiments []Experiment `json:"experiments"`
+}
+
+// Store holds the configured profiles and experiments and reloads them when
+// the file changes.
+type Store struct {
+	path string
+
+	mu          sync.RWMutex
+	profiles    map[string]Profile
+	def         string
+	experiments []Experiment
+	modTime     time.Time
+	onReload    []func()
+}
+
+// NewStore loads profiles from path. An empty path serves only DefaultProfile.
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	s.set(map[string]Profile{DefaultName: DefaultProfile()}, DefaultName, nil)
+	if path == "" {
+		return s, nil
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the named profile, or the default profile for "".
+func (s *Store) Get(name string) (Profile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if name == "" {
+		name = s.def
+	}
+	p, ok := s.profiles[name]
+	if !ok {
+		return Profile{}, ErrUnknownProfile
+	}
+	return p, nil
+}
+
+// Names lists the configured profile names.
+func (s *Store) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.profiles))
+	for name := range s.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OnReload registers fn to run after every successful reload.
+func (s *Store) OnReload(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onReload = append(s.onReload, fn)
+}
+
+// Watch polls the file every interval and reloads it when its modification
+// time changes. A broken file is logged and the previous profiles are kept.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	if s.path == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				info, err := os.Stat(s.path)
+				if err != nil {
+					continue
+				}
+				s.mu.RLock()
+				changed := !info.ModTime().Equal(s.modTime)
+				s.mu.RUnlock()
+				if !changed {
+					continue
+				}
+				if err := s.reload(); err != nil {
+					log.Printf("failed to reload ranking profiles: %v", err)
+					continue
+				}
+				log.Printf("ranking profiles reloaded from %s", s.path)
+			}
+		}
+	}()
+}
+
+func (s *Store) reload() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("parse %s: %w", s.path, err)
+	}
+
+	profiles := map[string]Profile{DefaultName: DefaultProfile()}
+	for _, p := range f.Profiles {
+		if p.Name == "" {
+			return fmt.Errorf("parse %s: profile without name", s.path)
+		}
+		if len(p.Fields) == 0 {
+			return fmt.Errorf("parse %s: profile %q has no fields", s.path, p.Name)
+		}
+		profiles[p.Name] = p
+	}
+	def := f.Default
+	if def == "" {
+		def = DefaultName
+	}
+	if _, ok := profiles[def]; !ok {
+		return fmt.Errorf("parse %s: default profile %q is not defined", s.path, def)
+	}
+	if err := validateExperiments(f.Experiments, profiles); err != nil {
+		return fmt.Errorf("parse %s: %w", s.path, err)
+	}
+
+	s.set(profiles, def, f.Experiments)
+	s.mu.Lock()
+	s.modTime = info.ModTime()
+	hooks := append([]func(){}, s.onReload...)
+	s.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn()
+	}
+	return nil
+}
+
+func (s *Store) set(profiles map[string]Profile, def string, experiments []Experiment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profiles = profiles
+	s.def = def
+	s.experiments = experiments
+}
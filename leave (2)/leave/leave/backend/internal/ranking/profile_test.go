@@ -0,0 +1,49 @@
+package ranking
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ranking.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"default": "fresh",
+		"profiles": [{"name": "fresh", "fields": {"title": 2, "content": 1}, "phrase_boost": 1.5}]
+	}`), 0o644))
+
+	s, err := NewStore(path)
+	require.NoError(t, err)
+
+	p, err := s.Get("")
+	require.NoError(t, err)
+	assert.Equal(t, "fresh", p.Name)
+	assert.Equal(t, []string{"content", "title^2"}, p.FieldList())
+	assert.Equal(t, []string{"default", "fresh"}, s.Names())
+
+	_, err = s.Get("missing")
+	assert.ErrorIs(t, err, ErrUnknownProfile)
+
+	reloaded := 0
+	s.OnReload(func() { reloaded++ })
+
+	// 配置错误时保留原有方案
+	require.NoError(t, os.WriteFile(path, []byte(`{"default": "gone"}`), 0o644))
+	assert.Error(t, s.reload())
+	p, err = s.Get("")
+	require.NoError(t, err)
+	assert.Equal(t, "fresh", p.Name)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"profiles": [{"name": "title_only", "fields": {"title": 1}}]}`), 0o644))
+	require.NoError(t, s.reload())
+	p, err = s.Get("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultName, p.Name)
+	_, err = s.Get("title_only")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reloaded)
+}
@@ -0,0 +1,208 @@
+// Package related mines related searches from session reformulations and
+// overlapping clicks, and precomputes them into the cache.
+package related
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"search-engine-backend/internal/cache"
+	"search-engine-backend/internal/storage"
+)
+
+const (
+	// MaxRelated 每个查询保留的相关搜索数量
+	MaxRelated = 8
+
+	sessionBatch  = 500
+	clickWindow   = 7 * 24 * time.Hour
+	minCooccur    = 2   // 会话共现次数下限，过滤偶然的组合
+	minOverlap    = 0.1 // 点击文档 Jaccard 相似度下限
+	candidateSize = 50
+)
+
+// Miner 周期性挖掘相关搜索
+type Miner struct {
+	cache *cache.CacheService
+	db    *storage.DB
+	now   func() time.Time
+	// clicked 上次挖掘时有点击重合的查询；点击移出统计窗口后需重写这些查询的相关搜索，
+	// 否则过期的组合会一直保留到相关搜索缓存过期
+	clicked map[string]bool
+}
+
+func NewMiner(cacheSvc *cache.CacheService, db *storage.DB) *Miner {
+	return &Miner{cache: cacheSvc, db: db, now: time.Now, clicked: make(map[string]bool)}
+}
+
+// Run mines every interval until ctx is done.
+func (m *Miner) Run(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.Mine(ctx); err != nil {
+					log.Printf("failed to mine related searches: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Mine folds ended sessions into the co-occurrence counts, computes click
+// overlap over the last week and rewrites the related set of every query
+// that changed, including queries whose click overlap has left the window.
+func (m *Miner) Mine(ctx context.Context) error {
+	touched := make(map[string]bool)
+	for {
+		sessions, err := m.cache.PopEndedSessions(ctx, sessionBatch)
+		if err != nil {
+			return err
+		}
+		if len(sessions) == 0 {
+			break
+		}
+		pairs := reformulations(sessions)
+		if err := m.cache.AddCooccurrences(ctx, pairs); err != nil {
+			return err
+		}
+		for p := range pairs {
+			touched[p[0]], touched[p[1]] = true, true
+		}
+	}
+
+	now := m.now()
+	clicks, err := m.db.ClickedDocs(now.Add(-clickWindow), now)
+	if err != nil {
+		return err
+	}
+	overlap := clickOverlap(clicks)
+	for q := range m.clicked {
+		touched[q] = true
+	}
+	clicked := make(map[string]bool, len(overlap))
+	for q := range overlap {
+		touched[q] = true
+		clicked[q] = true
+	}
+
+	for q := range touched {
+		cooc, err := m.cache.Cooccurrences(ctx, q, candidateSize)
+		if err != nil {
+			return err
+		}
+		if err := m.cache.SetRelated(ctx, q, rank(cooc, overlap[q])); err != nil {
+			return err
+		}
+	}
+	m.clicked = clicked
+	return nil
+}
+
+// reformulations counts consecutive distinct queries of a session as a
+// reformulation pair, once per session.
+func reformulations(sessions [][]string) map[[2]string]float64 {
+	pairs := make(map[[2]string]float64)
+	for _, seq := range sessions {
+		seen := make(map[[2]string]bool)
+		for i := 1; i < len(seq); i++ {
+			a, b := seq[i-1], seq[i]
+			if a == b {
+				continue
+			}
+			if b < a {
+				a, b = b, a
+			}
+			p := [2]string{a, b}
+			if !seen[p] {
+				seen[p] = true
+				pairs[p]++
+			}
+		}
+	}
+	return pairs
+}
+
+// clickOverlap returns the Jaccard similarity of the clicked documents of
+// every pair of queries sharing at least one click.
+func clickOverlap(clicks []storage.QueryDoc) map[string]map[string]float64 {
+	docs := make(map[string]map[string]bool)  // 查询 -> 点击文档
+	byDoc := make(map[string]map[string]bool) // 文档 -> 查询
+	for _, c := range clicks {
+		if docs[c.Query] == nil {
+			docs[c.Query] = make(map[string]bool)
+		}
+		docs[c.Query][c.DocID] = true
+		if byDoc[c.DocID] == nil {
+			byDoc[c.DocID] = make(map[string]bool)
+		}
+		byDoc[c.DocID][c.Query] = true
+	}
+
+	shared := make(map[[2]string]int)
+	for _, queries := range byDoc {
+		for a := range queries {
+			for b := range queries {
+				if a < b {
+					shared[[2]string{a, b}]++
+				}
+			}
+		}
+	}
+
+	overlap := make(map[string]map[string]float64)
+	for p, n := range shared {
+		j := float64(n) / float64(len(docs[p[0]])+len(docs[p[1]])-n)
+		if j < minOverlap {
+			continue
+		}
+		for _, d := range [][2]string{p, {p[1], p[0]}} {
+			if overlap[d[0]] == nil {
+				overlap[d[0]] = make(map[string]float64)
+			}
+			overlap[d[0]][d[1]] = j
+		}
+	}
+	return overlap
+}
+
+// rank combines the session co-occurrence, normalized by the strongest
+// one, with click overlap and returns the top MaxRelated queries.
+func rank(cooc []cache.HotQuery, overlap map[string]float64) []string {
+	scores := make(map[string]float64)
+	max := 0.0
+	for _, c := range cooc {
+		if c.Score >= minCooccur && c.Score > max {
+			max = c.Score
+		}
+	}
+	for _, c := range cooc {
+		if c.Score >= minCooccur {
+			scores[c.Query] += c.Score / max
+		}
+	}
+	for q, j := range overlap {
+		scores[q] += j
+	}
+
+	queries := make([]string, 0, len(scores))
+	for q := range scores {
+		queries = append(queries, q)
+	}
+	sort.Slice(queries, func(i, j int) bool {
+		if scores[queries[i]] != scores[queries[j]] {
+			return scores[queries[i]] > scores[queries[j]]
+		}
+		return queries[i] < queries[j]
+	})
+	if len(queries) > MaxRelated {
+		queries = queries[:MaxRelated]
+	}
+	return queries
+}
@@ -0,0 +1,82 @@
+package related
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"search-engine-backend/internal/cache"
+	"search-engine-backend/internal/storage"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReformulations(t *testing.T) {
+	pairs := reformulations([][]string{
+		{"golang", "go tutorial", "golang", "go tutorial"},
+		{"go tutorial", "golang"},
+		{"rust"},
+	})
+	assert.Equal(t, map[[2]string]float64{{"go tutorial", "golang"}: 2}, pairs)
+}
+
+func TestClickOverlap(t *testing.T) {
+	overlap := clickOverlap([]storage.QueryDoc{
+		{Query: "golang", DocID: "1"}, {Query: "golang", DocID: "2"},
+		{Query: "go lang", DocID: "1"}, {Query: "go lang", DocID: "2"}, {Query: "go lang", DocID: "3"},
+		{Query: "rust", DocID: "4"},
+	})
+	assert.InDelta(t, 2.0/3, overlap["golang"]["go lang"], 1e-9)
+	assert.Equal(t, overlap["golang"]["go lang"], overlap["go lang"]["golang"])
+	assert.NotContains(t, overlap, "rust")
+}
+
+func TestMine(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := cache.NewCacheService(mr.Addr(), "", cache.Options{Namespace: "test:"})
+	t.Cleanup(func() { c.Close() })
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// 两个已结束的会话都把 "golang" 改写为 "go tutorial"
+	ended := float64(time.Now().Add(-time.Hour).Unix())
+	for _, id := range []string{"s1", "s2"} {
+		mr.RPush("test:session:"+id, "golang", "go tutorial")
+		mr.ZAdd("test:sessions:active", ended, id)
+	}
+	// "golang" 与 "go lang" 的点击文档相同
+	now := time.Now()
+	require.NoError(t, db.SaveQueryLogs([]storage.QueryLog{
+		{SearchID: "a", Query: "golang", CreatedAt: now},
+		{SearchID: "b", Query: "go lang", CreatedAt: now},
+	}))
+	require.NoError(t, db.SaveClickLogs([]storage.ClickLog{
+		{SearchID: "a", DocID: "1", CreatedAt: now},
+		{SearchID: "b", DocID: "1", CreatedAt: now},
+	}))
+
+	m := NewMiner(c, db)
+	require.NoError(t, m.Mine(ctx))
+
+	related, err := c.Related(ctx, "golang")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go lang", "go tutorial"}, related, "ties are ordered alphabetically")
+
+	related, err = c.Related(ctx, "go tutorial")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"golang"}, related)
+
+	// 点击移出统计窗口后，即使没有新的会话，也要去掉只由点击得到的相关搜索
+	m.now = func() time.Time { return now.Add(clickWindow + time.Hour) }
+	require.NoError(t, m.Mine(ctx))
+	related, err = c.Related(ctx, "golang")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go tutorial"}, related)
+	related, err = c.Related(ctx, "go lang")
+	require.NoError(t, err)
+	assert.Empty(t, related)
+}
@@ -0,0 +1,139 @@
+// Package releval computes offline relevance metrics from graded judgments.
+package releval
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Judgments 人工标注：查询 -> URL -> 相关度等级 (0 表示不相关)
+type Judgments map[string]map[string]int
+
+// LoadJudgments reads tab-separated lines of query, URL and grade.
+// Blank lines and lines starting with '#' are ignored.
+func LoadJudgments(r io.Reader) (Judgments, error) {
+	j := make(Judgments)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.Split(text, "\t")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("line %d: want query<TAB>url<TAB>grade", line)
+		}
+		grade, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+		if err != nil || grade < 0 {
+			return nil, fmt.Errorf("line %d: invalid grade %q", line, parts[2])
+		}
+		query, url := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if j[query] == nil {
+			j[query] = make(map[string]int)
+		}
+		j[query][url] = grade
+	}
+	return j, scanner.Err()
+}
+
+// Queries returns the judged queries in a stable order.
+func (j Judgments) Queries() []string {
+	queries := make([]string, 0, len(j))
+	for q := range j {
+		queries = append(queries, q)
+	}
+	sort.Strings(queries)
+	return queries
+}
+
+// Metrics 单个查询或整体平均的指标
+type Metrics struct {
+	NDCG      float64
+	MRR       float64
+	Precision float64
+}
+
+// Evaluate scores a ranked list of URLs against the judgments of one query.
+// Unjudged URLs count as grade 0.
+func Evaluate(ranked []string, judged map[string]int, k int) Metrics {
+	if len(ranked) > k {
+		ranked = ranked[:k]
+	}
+
+	var m Metrics
+	dcg, relevant := 0.0, 0
+	for i, url := range ranked {
+		grade := judged[url]
+		if grade <= 0 {
+			continue
+		}
+		dcg += gain(grade) / math.Log2(float64(i+2))
+		relevant++
+		if m.MRR == 0 {
+			m.MRR = 1 / float64(i+1)
+		}
+	}
+
+	grades := make([]int, 0, len(judged))
+	for _, g := range judged {
+		if g > 0 {
+			grades = append(grades, g)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(grades)))
+	idcg := 0.0
+	for i, g := range grades {
+		if i >= k {
+			break
+		}
+		idcg += gain(g) / math.Log2(float64(i+2))
+	}
+	if idcg > 0 {
+		m.NDCG = dcg / idcg
+	}
+	m.Precision = float64(relevant) / float64(k)
+	return m
+}
+
+func gain(grade int) float64 {
+	return math.Pow(2, float64(grade)) - 1
+}
+
+// Retriever returns the ranked URLs for a query.
+type Retriever func(ctx context.Context, query string) ([]string, error)
+
+// Report 一次评测的结果
+type Report struct {
+	Mean     Metrics
+	PerQuery map[string]Metrics
+}
+
+// Run evaluates every judged query and averages the metrics.
+func Run(ctx context.Context, retrieve Retriever, j Judgments, k int) (*Report, error) {
+	report := &Report{PerQuery: make(map[string]Metrics, len(j))}
+	for _, q := range j.Queries() {
+		ranked, err := retrieve(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("query %q: %w", q, err)
+		}
+		m := Evaluate(ranked, j[q], k)
+		report.PerQuery[q] = m
+		report.Mean.NDCG += m.NDCG
+		report.Mean.MRR += m.MRR
+		report.Mean.Precision += m.Precision
+	}
+	if n := float64(len(report.PerQuery)); n > 0 {
+		report.Mean.NDCG /= n
+		report.Mean.MRR /= n
+		report.Mean.Precision /= n
+	}
+	return report, nil
+}
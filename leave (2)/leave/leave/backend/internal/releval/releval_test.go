@@ -0,0 +1,49 @@
+package releval
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadJudgments(t *testing.T) {
+	j, err := LoadJudgments(strings.NewReader("# query\turl\tgrade\ngo web\thttps://a\t2\n\ngo web\thttps://b\t0\n"))
+	require.NoError(t, err)
+	assert.Equal(t, Judgments{"go web": {"https://a": 2, "https://b": 0}}, j)
+
+	_, err = LoadJudgments(strings.NewReader("go web\thttps://a\n"))
+	assert.Error(t, err)
+}
+
+func TestEvaluate(t *testing.T) {
+	judged := map[string]int{"a": 3, "b": 1, "c": 0}
+
+	perfect := Evaluate([]string{"a", "b", "c"}, judged, 10)
+	assert.InDelta(t, 1.0, perfect.NDCG, 1e-9)
+	assert.Equal(t, 1.0, perfect.MRR)
+	assert.InDelta(t, 0.2, perfect.Precision, 1e-9)
+
+	swapped := Evaluate([]string{"x", "b", "a"}, judged, 10)
+	assert.Equal(t, 0.5, swapped.MRR)
+	assert.Less(t, swapped.NDCG, perfect.NDCG)
+
+	assert.Equal(t, Metrics{}, Evaluate(nil, judged, 10))
+}
+
+func TestRun(t *testing.T) {
+	j := Judgments{"q1": {"a": 1}, "q2": {"b": 1}}
+	retrieve := func(ctx context.Context, q string) ([]string, error) {
+		if q == "q1" {
+			return []string{"a"}, nil
+		}
+		return []string{"x", "b"}, nil
+	}
+
+	report, err := Run(context.Background(), retrieve, j, 10)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.75, report.Mean.MRR, 1e-9)
+	assert.Len(t, report.PerQuery, 2)
+}
@@ -0,0 +1,81 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"search-engine-backend/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	assert.True(t, b.Allow())
+	b.Failure()
+	assert.True(t, b.Allow(), "below threshold stays closed")
+	b.Failure()
+	assert.False(t, b.Allow(), "threshold reached opens the breaker")
+
+	now = now.Add(time.Minute)
+	assert.True(t, b.Allow(), "cooldown elapsed lets one probe through")
+	assert.False(t, b.Allow(), "only one probe while half-open")
+
+	b.Failure()
+	assert.False(t, b.Allow(), "failed probe reopens the breaker")
+
+	now = now.Add(time.Minute)
+	assert.True(t, b.Allow())
+	b.Success()
+	assert.True(t, b.Allow())
+	assert.True(t, b.Allow())
+}
+
+func TestCircuitBreakerCancelledProbe(t *testing.T) {
+	var slow atomic.Bool
+	slow.Store(true)
+	stop := make(chan struct{})
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		if slow.Load() {
+			select {
+			case <-r.Context().Done():
+			case <-stop:
+			}
+			return
+		}
+		w.Write([]byte(`{"took":1,"hits":{"total":{"value":0},"hits":[]}}`))
+	}))
+	defer es.Close()
+	defer close(stop)
+
+	engine, err := NewESEngine(&config.Config{ElasticsearchURL: es.URL, SearchTimeout: 5 * time.Second, BreakerThreshold: 1, BreakerCooldown: time.Minute})
+	require.NoError(t, err)
+	e := engine.(*esEngine)
+	now := time.Now()
+	e.breaker.now = func() time.Time { return now }
+	e.breaker.Failure()
+	now = now.Add(time.Minute)
+
+	// 半开状态的探测请求被调用方取消
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = e.Search(ctx, Query{Text: "go", Size: 10})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 下一个请求重新成为探测请求，而不是一直被熔断拒绝
+	assert.True(t, e.breaker.Allow())
+	e.breaker.Release()
+	slow.Store(false)
+	_, err = e.Search(context.Background(), Query{Text: "go", Size: 10})
+	require.NoError(t, err)
+	assert.True(t, e.breaker.Allow(), "successful probe closes the breaker")
+}
@@ -0,0 +1,131 @@
+package search
+
+import (
+	"context"
+	"log"
+	"sort"
+)
+
+// Curation 运营对某个查询的人工干预
+type Curation struct {
+	Pins   []Pin
+	Hidden []string
+}
+
+// Pin 将文档固定在第 Position 位（从 1 开始，跨分页计算）
+type Pin struct {
+	DocID    string
+	Position int
+}
+
+// Curator looks up the curation that applies to a normalized query.
+type Curator interface {
+	Lookup(query string) (Curation, bool)
+	// OnChange registers fn to run after the rules change.
+	OnChange(fn func())
+}
+
+// SetCurator enables curated results. Cached results are dropped whenever
+// the rules change.
+func (s *Service) SetCurator(c Curator) {
+	s.curator = c
+	if s.cache != nil {
+		c.OnChange(func() {
+			if err := s.cache.Invalidate(context.Background(), "search:"); err != nil {
+				log.Printf("failed to invalidate search cache after curation change: %v", err)
+			}
+		})
+	}
+}
+
+// curation returns the curation that applies to query, if any.
+func (s *Service) curation(query string) (Curation, bool) {
+	if s.curator == nil {
+		return Curation{}, false
+	}
+	return s.curator.Lookup(NormalizeQuery(query))
+}
+
+// window returns how many organic hits to retrieve, starting from the first
+// one, for page. Pinned and hidden documents may rank anywhere before the
+// page, and pins on earlier pages take slots there, so the organic offset of
+// the page is only known once the whole prefix has been curated.
+func (c Curation) window(page, size int) int {
+	return page*size + len(c.Pins) + len(c.Hidden)
+}
+
+// curate hides documents and places pinned documents on the requested page.
+// Pinned documents are removed from their organic positions on every page,
+// and pins on earlier pages shift the organic hits of this page back by one
+// position each. result holds the first c.window(page, size) organic hits.
+func (s *Service) curate(ctx context.Context, query string, c Curation, result *SearchResult, page, size int) {
+	removed := make(map[string]bool, len(c.Hidden)+len(c.Pins))
+	for _, id := range c.Hidden {
+		removed[id] = true
+	}
+	offset := (page - 1) * size
+	var ids []string
+	for _, p := range c.Pins {
+		removed[p.DocID] = true
+		if p.Position <= offset+size {
+			ids = append(ids, p.DocID)
+		}
+	}
+
+	byID := make(map[string]Document, len(ids))
+	if len(ids) > 0 {
+		docs, err := s.engine.Documents(ctx, ids)
+		if err != nil {
+			log.Printf("failed to fetch pinned documents for %q: %v", query, err)
+		}
+		for _, doc := range docs {
+			byID[doc.ID] = doc
+		}
+	}
+
+	// 前面各页中实际存在的置顶文档各占一个位置
+	var pins []Pin
+	start := offset
+	for _, p := range c.Pins {
+		if _, ok := byID[p.DocID]; !ok {
+			continue
+		}
+		if p.Position <= offset {
+			start--
+		} else if p.Position <= offset+size {
+			pins = append(pins, p)
+		}
+	}
+
+	organic := make([]Document, 0, len(result.Hits))
+	for _, doc := range result.Hits {
+		if !removed[doc.ID] {
+			organic = append(organic, doc)
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > len(organic) {
+		start = len(organic)
+	}
+	hits := make([]Document, 0, size+len(pins))
+	hits = append(hits, organic[start:]...)
+
+	sort.Slice(pins, func(i, j int) bool { return pins[i].Position < pins[j].Position })
+	for _, p := range pins {
+		doc := byID[p.DocID]
+		doc.Pinned = true
+		i := p.Position - 1 - offset
+		if i > len(hits) {
+			i = len(hits)
+		}
+		hits = append(hits[:i], append([]Document{doc}, hits[i:]...)...)
+	}
+	if len(hits) > size {
+		hits = hits[:size]
+	}
+
+	result.Hits = hits
+	result.Curated = true
+}
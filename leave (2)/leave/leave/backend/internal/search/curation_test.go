@@ -0,0 +1,95 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"search-engine-backend/internal/config"
+	"search-engine-backend/internal/ranking"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticCurator map[string]Curation
+
+func (c staticCurator) Lookup(query string) (Curation, bool) {
+	cur, ok := c[query]
+	return cur, ok
+}
+
+func (c staticCurator) OnChange(fn func()) {}
+
+// curatedPage runs the retrieval and curation steps of Service.Search.
+func curatedPage(t *testing.T, svc *Service, query string, page, size int) *SearchResult {
+	ctx := context.Background()
+	profile, err := svc.profiles.Get("")
+	require.NoError(t, err)
+	from, n := (page-1)*size, size
+	c, curated := svc.curation(query)
+	if curated {
+		from, n = 0, c.window(page, size)
+	}
+	result, err := svc.retrieve(ctx, query, profile, "", from, n)
+	require.NoError(t, err)
+	if curated {
+		svc.curate(ctx, query, c, result, page, size)
+	}
+	return result
+}
+
+func TestCurate(t *testing.T) {
+	profiles, err := ranking.NewStore("")
+	require.NoError(t, err)
+	svc := NewServiceWithEngine(&config.Config{}, nil, profiles, newTestEngine(t))
+	svc.SetCurator(staticCurator{
+		"web": {Pins: []Pin{{DocID: "2", Position: 1}, {DocID: "3", Position: 3}, {DocID: "missing", Position: 2}}, Hidden: []string{"4"}},
+	})
+	ctx := context.Background()
+
+	result, err := svc.Retrieve(ctx, "web", 1, 10, SearchOptions{})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"1", "4"}, ids(result.Hits))
+
+	result = curatedPage(t, svc, " WEB ", 1, 10)
+	assert.True(t, result.Curated)
+	assert.Equal(t, []string{"2", "1", "3"}, ids(result.Hits))
+	assert.True(t, result.Hits[0].Pinned)
+	assert.False(t, result.Hits[1].Pinned)
+
+	// 第 2 页只放置落在该页的置顶文档
+	page2 := curatedPage(t, svc, "web", 2, 2)
+	assert.Equal(t, []string{"3"}, ids(page2.Hits))
+
+	other := curatedPage(t, svc, "go web", 1, 10)
+	assert.False(t, other.Curated)
+}
+
+func TestCuratePageBoundary(t *testing.T) {
+	profiles, err := ranking.NewStore("")
+	require.NoError(t, err)
+	engine := newTestEngine(t)
+	for _, id := range []string{"5", "6", "7"} {
+		require.NoError(t, engine.Index(context.Background(), &Document{ID: id, Title: "Web page " + id, URL: "https://example.com/" + id}))
+	}
+	svc := NewServiceWithEngine(&config.Config{}, nil, profiles, engine)
+	svc.SetCurator(staticCurator{"web": {Pins: []Pin{{DocID: "2", Position: 1}}, Hidden: []string{"4"}}})
+
+	organic, err := svc.Retrieve(context.Background(), "web", 1, 10, SearchOptions{})
+	require.NoError(t, err)
+	var want []string
+	for _, id := range ids(organic.Hits) {
+		if id != "4" {
+			want = append(want, id)
+		}
+	}
+	require.Len(t, want, 4)
+
+	// 第 1 页的置顶文档占掉一个位置，第 2 页从第 2 条自然结果接着往后排，不丢结果
+	page1 := curatedPage(t, svc, "web", 1, 2)
+	assert.Equal(t, []string{"2", want[0]}, ids(page1.Hits))
+	page2 := curatedPage(t, svc, "web", 2, 2)
+	assert.Equal(t, want[1:3], ids(page2.Hits))
+	page3 := curatedPage(t, svc, "web", 3, 2)
+	assert.Equal(t, want[3:], ids(page3.Hits))
+}
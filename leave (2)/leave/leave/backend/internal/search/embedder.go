@@ -0,0 +1,87 @@
+package search
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+)
+
+// Embedder turns text into a dense vector for kNN retrieval. Vectors of
+// one Embedder must all have Dims() dimensions.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+	Dims() int
+}
+
+// HashingEmbedder is a deterministic Embedder based on feature hashing of
+// words and character trigrams. It needs no model, so it is the default
+// for tests; trigrams give it some tolerance to inflections and typos but
+// it captures no real semantics.
+type HashingEmbedder struct {
+	dims int
+}
+
+func NewHashingEmbedder(dims int) *HashingEmbedder {
+	return &HashingEmbedder{dims: dims}
+}
+
+func (e *HashingEmbedder) Dims() int {
+	return e.dims
+}
+
+// Embed returns the L2-normalized hashed features of text.
+func (e *HashingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vec := make([]float64, e.dims)
+	add := func(feature string, weight float64) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		// 最高位决定符号，减少哈希冲突带来的偏差
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vec[sum%uint64(e.dims)] += weight
+	}
+
+	for _, token := range analyze(text) {
+		add("w:"+token, 1)
+		runes := []rune("#" + token + "#")
+		if len(runes) < 5 {
+			continue
+		}
+		for i := 0; i+3 <= len(runes); i++ {
+			add("g:"+string(runes[i:i+3]), 0.5)
+		}
+	}
+
+	norm := 0.0
+	for _, v := range vec {
+		norm += v * v
+	}
+	out := make([]float32, e.dims)
+	if norm == 0 {
+		return out, nil
+	}
+	norm = math.Sqrt(norm)
+	for i, v := range vec {
+		out[i] = float32(v / norm)
+	}
+	return out, nil
+}
+
+// cosine returns the cosine similarity of two vectors, 0 if either is zero.
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,37 @@
+package search
+
+import (
+	"context"
+
+	"search-engine-backend/internal/ranking"
+)
+
+// Engine executes retrieval requests against a document index. The
+// Elasticsearch engine serves production traffic; MemoryEngine backs tests
+// and offline relevance evaluation.
+type Engine interface {
+	Search(ctx context.Context, q Query) (*SearchResult, error)
+	// CorrectSpelling returns the corrected text, or "" if nothing changed.
+	CorrectSpelling(ctx context.Context, text string) (string, error)
+	Index(ctx context.Context, doc *Document) error
+	// KNN returns the k nearest documents to an embedding vector.
+	KNN(ctx context.Context, vector []float32, k int) (*SearchResult, error)
+	// Documents fetches documents by ID, skipping IDs that do not exist.
+	Documents(ctx context.Context, ids []string) ([]Document, error)
+}
+
+// Query 一次检索请求
+type Query struct {
+	Text    string
+	Match   MatchOptions
+	Profile ranking.Profile
+	From    int
+	Size    int
+}
+
+// MatchOptions 控制查询词的匹配方式
+type MatchOptions struct {
+	Operator           string // "and" 或 "or"
+	MinimumShouldMatch string // 例如 "75%" 或 "2"
+	Fuzziness          string // 例如 "AUTO"
+}
@@ -0,0 +1,415 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"search-engine-backend/internal/config"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// errUnavailable marks ES failures that count against the circuit breaker:
+// transport errors, timeouts and 5xx responses.
+var errUnavailable = errors.New("elasticsearch unavailable")
+
+// esEngine runs queries against the "webpages" index through a circuit breaker.
+type esEngine struct {
+	client  *elasticsearch.Client
+	breaker *circuitBreaker
+	timeout time.Duration
+	// vectorMapped 表示 embedding 字段的 dense_vector 映射已确认存在
+	vectorMapped atomic.Bool
+}
+
+// NewESEngine creates the Elasticsearch-backed Engine.
+func NewESEngine(cfg *config.Config) (Engine, error) {
+	esCfg := elasticsearch.Config{
+		Addresses: []string{cfg.ElasticsearchURL},
+	}
+	esClient, err := elasticsearch.NewClient(esCfg)
+	if err != nil {
+		return nil, fmt.Errorf("error creating elasticsearch client: %s", err)
+	}
+	return &esEngine{
+		client:  esClient,
+		breaker: newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+		timeout: cfg.SearchTimeout,
+	}, nil
+}
+
+// Search executes one match query and parses the hits.
+func (e *esEngine) Search(ctx context.Context, q Query) (*SearchResult, error) {
+	r, err := e.doSearch(ctx, buildSearchBody(q))
+	if err != nil {
+		return nil, err
+	}
+	return parseSearchResponse(r), nil
+}
+
+// KNN runs an approximate kNN search on the embedding field.
+func (e *esEngine) KNN(ctx context.Context, vector []float32, k int) (*SearchResult, error) {
+	r, err := e.doSearch(ctx, map[string]interface{}{
+		"size": k,
+		"knn": map[string]interface{}{
+			"field":          "embedding",
+			"query_vector":   vector,
+			"k":              k,
+			"num_candidates": k * 10,
+		},
+		"_source": map[string]interface{}{"excludes": []string{"embedding"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseSearchResponse(r), nil
+}
+
+func parseSearchResponse(r map[string]interface{}) *SearchResult {
+	// 5. Parse Response
+	hits := r["hits"].(map[string]interface{})
+	total := int64(hits["total"].(map[string]interface{})["value"].(float64))
+	took := int(r["took"].(float64))
+
+	var documents []Document
+	for _, hit := range hits["hits"].([]interface{}) {
+		h := hit.(map[string]interface{})
+		doc := documentFromSource(h["_id"].(string), h["_source"].(map[string]interface{}))
+		doc.Score = h["_score"].(float64)
+		documents = append(documents, doc)
+	}
+
+	return &SearchResult{
+		Total: total,
+		Hits:  documents,
+		Took:  took,
+	}
+}
+
+func documentFromSource(id string, source map[string]interface{}) Document {
+	doc := Document{ID: id}
+	doc.Title, _ = source["title"].(string)
+	doc.Content, _ = source["content"].(string)
+	doc.URL, _ = source["url"].(string)
+	doc.Category, _ = source["category"].(string)
+	doc.CategoryConfidence, _ = source["category_confidence"].(float64)
+	doc.Classified, _ = source["classified"].(bool)
+	return doc
+}
+
+// Documents fetches documents with a multi-get request.
+func (e *esEngine) Documents(ctx context.Context, ids []string) ([]Document, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"ids": ids}); err != nil {
+		return nil, err
+	}
+
+	esCtx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	res, err := e.client.Mget(&buf,
+		e.client.Mget.WithContext(esCtx),
+		e.client.Mget.WithIndex("webpages"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("mget request failed: %s", res.String())
+	}
+	var r struct {
+		Docs []struct {
+			ID     string                 `json:"_id"`
+			Found  bool                   `json:"found"`
+			Source map[string]interface{} `json:"_source"`
+		} `json:"docs"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+
+	var docs []Document
+	for _, d := range r.Docs {
+		if d.Found {
+			docs = append(docs, documentFromSource(d.ID, d.Source))
+		}
+	}
+	return docs, nil
+}
+
+// doSearch sends a search body to ES through the circuit breaker and
+// returns the decoded response.
+func (e *esEngine) doSearch(ctx context.Context, body map[string]interface{}) (map[string]interface{}, error) {
+	if !e.breaker.Allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		e.breaker.Release()
+		return nil, err
+	}
+
+	// 4. Execute Search
+	esCtx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	res, err := e.client.Search(
+		e.client.Search.WithContext(esCtx),
+		e.client.Search.WithIndex("webpages"),
+		e.client.Search.WithBody(&buf),
+		e.client.Search.WithTrackTotalHits(true),
+	)
+	if err != nil {
+		if ctx.Err() != nil {
+			// 调用方已取消，不计入 ES 失败，但要释放半开状态的探测名额
+			e.breaker.Release()
+			return nil, ctx.Err()
+		}
+		e.breaker.Failure()
+		return nil, fmt.Errorf("%w: %v", errUnavailable, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode >= 500 {
+			e.breaker.Failure()
+			return nil, fmt.Errorf("%w: %s", errUnavailable, res.String())
+		}
+		e.breaker.Success()
+		return nil, fmt.Errorf("search request failed: %s", res.String())
+	}
+
+	var r map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		e.breaker.Failure()
+		return nil, fmt.Errorf("%w: %v", errUnavailable, err)
+	}
+	e.breaker.Success()
+	return r, nil
+}
+
+// CorrectSpelling asks the ES term suggester for the most popular
+// correction of each term.
+func (e *esEngine) CorrectSpelling(ctx context.Context, text string) (string, error) {
+	r, err := e.doSearch(ctx, map[string]interface{}{
+		"size": 0,
+		"suggest": map[string]interface{}{
+			"text": text,
+			"spelling": map[string]interface{}{
+				"term": map[string]interface{}{
+					"field":        "content",
+					"suggest_mode": "popular",
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return applySpellingSuggestions(text, r), nil
+}
+
+// applySpellingSuggestions replaces each suggested span of text with its
+// top option. ES reports offsets in characters, not bytes.
+func applySpellingSuggestions(text string, r map[string]interface{}) string {
+	suggest, _ := r["suggest"].(map[string]interface{})
+	entries, _ := suggest["spelling"].([]interface{})
+	runes := []rune(text)
+
+	var b strings.Builder
+	pos, changed := 0, false
+	for _, e := range entries {
+		entry, _ := e.(map[string]interface{})
+		options, _ := entry["options"].([]interface{})
+		offset, _ := entry["offset"].(float64)
+		length, _ := entry["length"].(float64)
+		start, end := int(offset), int(offset)+int(length)
+		if len(options) == 0 || start < pos || end > len(runes) {
+			continue
+		}
+		option, _ := options[0].(map[string]interface{})
+		replacement, _ := option["text"].(string)
+		if replacement == "" {
+			continue
+		}
+		b.WriteString(string(runes[pos:start]))
+		b.WriteString(replacement)
+		pos, changed = end, true
+	}
+	if !changed {
+		return ""
+	}
+	b.WriteString(string(runes[pos:]))
+	return b.String()
+}
+
+func (e *esEngine) Index(ctx context.Context, doc *Document) error {
+	if len(doc.Embedding) > 0 && !e.vectorMapped.Load() {
+		if err := e.ensureVectorMapping(ctx, len(doc.Embedding)); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	req := esapi.IndexRequest{
+		Index:      "webpages",
+		DocumentID: doc.ID,
+		Body:       bytes.NewReader(data),
+		Refresh:    "true",
+	}
+
+	res, err := req.Do(ctx, e.client)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error indexing document: %s", res.String())
+	}
+	return nil
+}
+
+// Explain returns the analyzer tokens of every queried field, the search
+// body and the ES _explain breakdown of docID. It bypasses the circuit
+// breaker since it only serves admin debugging.
+func (e *esEngine) Explain(ctx context.Context, q Query, docID string) (*Explanation, error) {
+	esCtx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	tokens := make(map[string][]Token)
+	for field := range q.Profile.Fields {
+		t, err := e.analyze(esCtx, field, q.Text)
+		if err != nil {
+			return nil, err
+		}
+		tokens[field] = t
+	}
+
+	body := buildSearchBody(q)
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"query": body["query"]}); err != nil {
+		return nil, err
+	}
+	res, err := e.client.Explain("webpages", docID,
+		e.client.Explain.WithContext(esCtx),
+		e.client.Explain.WithBody(&buf),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, ErrDocumentNotFound
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("explain request failed: %s", res.String())
+	}
+	var r map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+
+	return &Explanation{
+		Tokens:  tokens,
+		ESQuery: body,
+		Explain: r,
+	}, nil
+}
+
+// analyze runs text through the analyzer configured for field.
+func (e *esEngine) analyze(ctx context.Context, field, text string) ([]Token, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"field": field, "text": text}); err != nil {
+		return nil, err
+	}
+	res, err := e.client.Indices.Analyze(
+		e.client.Indices.Analyze.WithContext(ctx),
+		e.client.Indices.Analyze.WithIndex("webpages"),
+		e.client.Indices.Analyze.WithBody(&buf),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("analyze request failed: %s", res.String())
+	}
+	var r struct {
+		Tokens []Token `json:"tokens"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	return r.Tokens, nil
+}
+
+// ensureVectorMapping maps the embedding field as an indexed dense_vector,
+// creating the index if needed. Dynamic mapping would store it as plain
+// floats, which kNN search cannot use.
+func (e *esEngine) ensureVectorMapping(ctx context.Context, dims int) error {
+	properties := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"embedding": map[string]interface{}{
+				"type":       "dense_vector",
+				"dims":       dims,
+				"index":      true,
+				"similarity": "cosine",
+			},
+		},
+	}
+
+	exists, err := e.client.Indices.Exists([]string{"webpages"}, e.client.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	exists.Body.Close()
+
+	var buf bytes.Buffer
+	var res *esapi.Response
+	if exists.StatusCode == 404 {
+		if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"mappings": properties}); err != nil {
+			return err
+		}
+		res, err = e.client.Indices.Create("webpages",
+			e.client.Indices.Create.WithContext(ctx),
+			e.client.Indices.Create.WithBody(&buf),
+		)
+	} else {
+		if err := json.NewEncoder(&buf).Encode(properties); err != nil {
+			return err
+		}
+		res, err = e.client.Indices.PutMapping([]string{"webpages"}, &buf,
+			e.client.Indices.PutMapping.WithContext(ctx),
+		)
+	}
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error mapping embedding field: %s", res.String())
+	}
+	e.vectorMapped.Store(true)
+	return nil
+}
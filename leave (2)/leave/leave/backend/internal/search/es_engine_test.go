@@ -0,0 +1,66 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"search-engine-backend/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplySpellingSuggestions(t *testing.T) {
+	var r map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"suggest": {"spelling": [
+			{"text": "搜锁", "offset": 0, "length": 2, "options": [{"text": "搜索", "score": 0.5, "freq": 10}]},
+			{"text": "golang", "offset": 3, "length": 6, "options": []},
+			{"text": "tutorail", "offset": 10, "length": 8, "options": [{"text": "tutorial", "score": 0.8, "freq": 42}]}
+		]}
+	}`), &r))
+
+	assert.Equal(t, "搜索 golang tutorial", applySpellingSuggestions("搜锁 golang tutorail", r))
+	assert.Equal(t, "", applySpellingSuggestions("golang", map[string]interface{}{}))
+}
+
+func TestESVectorMappingAndKNN(t *testing.T) {
+	var requests []string
+	var knn map[string]interface{}
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/webpages/_search":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			knn = body["knn"].(map[string]interface{})
+			w.Write([]byte(`{"took":1,"hits":{"total":{"value":1},"hits":[{"_id":"1","_score":0.9,"_source":{"title":"Go","content":"web","url":"https://go.dev"}}]}}`))
+		default:
+			w.Write([]byte(`{"acknowledged":true,"result":"created"}`))
+		}
+	}))
+	defer es.Close()
+
+	engine, err := NewESEngine(&config.Config{ElasticsearchURL: es.URL, SearchTimeout: time.Second, BreakerThreshold: 5})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, id := range []string{"1", "2"} {
+		require.NoError(t, engine.Index(ctx, &Document{ID: id, Embedding: []float32{0.6, 0.8}}))
+	}
+	assert.Equal(t, []string{"HEAD /webpages", "PUT /webpages", "PUT /webpages/_doc/1", "PUT /webpages/_doc/2"}, requests,
+		"the dense_vector mapping is created once")
+
+	result, err := engine.KNN(ctx, []float32{0.6, 0.8}, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "embedding", knn["field"])
+	assert.Equal(t, float64(5), knn["k"])
+	assert.Equal(t, []string{"1"}, ids(result.Hits))
+}
@@ -0,0 +1,119 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrExplainUnsupported is returned when the engine cannot explain scores.
+	ErrExplainUnsupported = errors.New("engine does not support explain")
+	// ErrDocumentNotFound is returned when the document to explain is not indexed.
+	ErrDocumentNotFound = errors.New("document not found")
+)
+
+// Explainer is implemented by engines that can break down the score of a
+// single document.
+type Explainer interface {
+	Explain(ctx context.Context, q Query, docID string) (*Explanation, error)
+}
+
+// Explanation 管理后台排查排序问题所需的全部信息
+type Explanation struct {
+	Query   string                 `json:"query"`
+	Profile string                 `json:"profile"`
+	AST     QueryNode              `json:"ast"`
+	Tokens  map[string][]Token     `json:"tokens"`
+	ESQuery map[string]interface{} `json:"es_query"`
+	Explain map[string]interface{} `json:"explain"`
+}
+
+// Token is one analyzer output token.
+type Token struct {
+	Token       string `json:"token"`
+	StartOffset int    `json:"start_offset"`
+	EndOffset   int    `json:"end_offset"`
+	Position    int    `json:"position"`
+	Type        string `json:"type"`
+}
+
+// QueryNode 查询语法树节点
+type QueryNode struct {
+	Type     string                 `json:"type"`
+	Value    string                 `json:"value,omitempty"`
+	Fields   []string               `json:"fields,omitempty"`
+	Params   map[string]interface{} `json:"params,omitempty"`
+	Children []QueryNode            `json:"children,omitempty"`
+}
+
+// Explain explains how the strict (first-stage) query scores docID.
+func (s *Service) Explain(ctx context.Context, query, docID string, opts SearchOptions) (*Explanation, error) {
+	profile, err := s.profiles.Get(opts.Profile)
+	if err != nil {
+		return nil, err
+	}
+	explainer, ok := s.engine.(Explainer)
+	if !ok {
+		return nil, ErrExplainUnsupported
+	}
+
+	q := Query{
+		Text:    strings.Join(strings.Fields(query), " "),
+		Match:   strictMatch(profile),
+		Profile: profile,
+		Size:    1,
+	}
+	ex, err := explainer.Explain(ctx, q, docID)
+	if err != nil {
+		return nil, err
+	}
+	ex.Query = q.Text
+	ex.Profile = profile.Name
+	ex.AST = parseQuery(q)
+	return ex, nil
+}
+
+// parseQuery describes how the service interprets a query: the matched
+// terms and the ranking signals the profile adds on top.
+func parseQuery(q Query) QueryNode {
+	terms := strings.Fields(q.Text)
+	match := QueryNode{
+		Type:   q.Match.Operator,
+		Fields: q.Profile.FieldList(),
+		Params: map[string]interface{}{},
+	}
+	if q.Match.MinimumShouldMatch != "" {
+		match.Params["minimum_should_match"] = q.Match.MinimumShouldMatch
+	}
+	if q.Match.Fuzziness != "" {
+		match.Params["fuzziness"] = q.Match.Fuzziness
+	}
+	for _, t := range terms {
+		match.Children = append(match.Children, QueryNode{Type: "term", Value: t})
+	}
+
+	root := QueryNode{Type: "query", Children: []QueryNode{match}}
+	if q.Profile.PhraseBoost > 0 {
+		root.Children = append(root.Children, QueryNode{
+			Type:   "phrase",
+			Value:  q.Text,
+			Params: map[string]interface{}{"slop": q.Profile.PhraseSlop, "boost": q.Profile.PhraseBoost},
+		})
+	}
+	if f := q.Profile.Freshness; f != nil && f.Weight > 0 {
+		root.Children = append(root.Children, QueryNode{
+			Type:   "freshness",
+			Fields: []string{"timestamp"},
+			Params: map[string]interface{}{"scale": f.Scale, "decay": f.Decay, "weight": f.Weight},
+		})
+	}
+	if q.Profile.AuthorityWeight > 0 {
+		root.Children = append(root.Children, QueryNode{
+			Type:   "authority",
+			Fields: []string{"authority"},
+			Params: map[string]interface{}{"weight": q.Profile.AuthorityWeight},
+		})
+	}
+	return root
+}
@@ -0,0 +1,70 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"search-engine-backend/internal/config"
+	"search-engine-backend/internal/ranking"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	profile := ranking.DefaultProfile()
+	profile.PhraseBoost = 2
+	profile.PhraseSlop = 1
+
+	ast := parseQuery(Query{Text: "go web", Match: strictMatch(profile), Profile: profile})
+	require.Len(t, ast.Children, 2)
+	match := ast.Children[0]
+	assert.Equal(t, "and", match.Type)
+	assert.Equal(t, []string{"content", "title^3"}, match.Fields)
+	assert.Equal(t, []QueryNode{{Type: "term", Value: "go"}, {Type: "term", Value: "web"}}, match.Children)
+	assert.Equal(t, "phrase", ast.Children[1].Type)
+}
+
+func TestESExplain(t *testing.T) {
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_analyze"):
+			w.Write([]byte(`{"tokens":[{"token":"go","start_offset":0,"end_offset":2,"position":0,"type":"<ALPHANUM>"}]}`))
+		case r.URL.Path == "/webpages/_explain/1":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Contains(t, body, "query")
+			w.Write([]byte(`{"_id":"1","matched":true,"explanation":{"value":1.5,"description":"sum of:"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"_id":"2","matched":false}`))
+		}
+	}))
+	defer es.Close()
+
+	engine, err := NewESEngine(&config.Config{ElasticsearchURL: es.URL, SearchTimeout: time.Second, BreakerThreshold: 5})
+	require.NoError(t, err)
+	profiles, err := ranking.NewStore("")
+	require.NoError(t, err)
+	svc := NewServiceWithEngine(&config.Config{}, nil, profiles, engine)
+
+	ex, err := svc.Explain(context.Background(), " go ", "1", SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "go", ex.Query)
+	assert.Equal(t, ranking.DefaultName, ex.Profile)
+	assert.Equal(t, "go", ex.Tokens["title"][0].Token)
+	assert.Contains(t, ex.ESQuery, "query")
+	assert.Equal(t, true, ex.Explain["matched"])
+
+	_, err = svc.Explain(context.Background(), "go", "2", SearchOptions{})
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	_, err = NewServiceWithEngine(&config.Config{}, nil, profiles, NewMemoryEngine()).Explain(context.Background(), "go", "1", SearchOptions{})
+	assert.ErrorIs(t, err, ErrExplainUnsupported)
+}
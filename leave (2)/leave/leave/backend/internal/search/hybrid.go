@@ -0,0 +1,67 @@
+package search
+
+import (
+	"context"
+	"log"
+	"sort"
+)
+
+// rrfK 倒数排名融合的平滑常数，沿用 RRF 论文的取值
+const rrfK = 60
+
+// SetEmbedder enables hybrid retrieval: documents are embedded when they
+// are indexed, and lexical results are fused with kNN results.
+func (s *Service) SetEmbedder(e Embedder) {
+	s.embedder = e
+}
+
+// fuseVectorHits fuses the top from+size lexical hits in result with as
+// many kNN hits and keeps size hits starting at from. If the vector side
+// fails the lexical ranking is kept.
+func (s *Service) fuseVectorHits(ctx context.Context, query string, result *SearchResult, from, size int) {
+	window := from + size
+	knn := &SearchResult{}
+	vec, err := s.embedder.Embed(ctx, query)
+	if err == nil {
+		knn, err = s.engine.KNN(ctx, vec, window)
+	}
+	if err != nil {
+		log.Printf("vector retrieval failed for %q, using lexical results only: %v", query, err)
+		knn = &SearchResult{}
+	}
+
+	fused := fuseRRF(result.Hits, knn.Hits)
+	if int64(len(fused)) > result.Total {
+		result.Total = int64(len(fused))
+	}
+	if from > len(fused) {
+		from = len(fused)
+	}
+	to := from + size
+	if to > len(fused) {
+		to = len(fused)
+	}
+	result.Hits = fused[from:to]
+}
+
+// fuseRRF merges ranked lists by reciprocal rank fusion: each document
+// scores Σ 1/(rrfK + rank). Ties keep the order of first appearance.
+func fuseRRF(lists ...[]Document) []Document {
+	scores := make(map[string]float64)
+	var fused []Document
+	for _, list := range lists {
+		for rank, doc := range list {
+			if _, seen := scores[doc.ID]; !seen {
+				fused = append(fused, doc)
+			}
+			scores[doc.ID] += 1 / float64(rrfK+rank+1)
+		}
+	}
+	for i := range fused {
+		fused[i].Score = scores[fused[i].ID]
+	}
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].Score > fused[j].Score
+	})
+	return fused
+}
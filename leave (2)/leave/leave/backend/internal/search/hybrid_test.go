@@ -0,0 +1,71 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"search-engine-backend/internal/config"
+	"search-engine-backend/internal/ranking"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashingEmbedder(t *testing.T) {
+	e := NewHashingEmbedder(256)
+	ctx := context.Background()
+
+	a, err := e.Embed(ctx, "web server")
+	require.NoError(t, err)
+	b, err := e.Embed(ctx, "Web  Server")
+	require.NoError(t, err)
+	assert.Equal(t, a, b, "embedding is deterministic and case-insensitive")
+	assert.Len(t, a, 256)
+	assert.InDelta(t, 1.0, cosine(a, a), 1e-6)
+
+	near, _ := e.Embed(ctx, "webservers")
+	far, _ := e.Embed(ctx, "css layout")
+	assert.Greater(t, cosine(a, near), cosine(a, far))
+
+	zero, _ := e.Embed(ctx, "")
+	assert.Equal(t, 0.0, cosine(a, zero))
+}
+
+func TestFuseRRF(t *testing.T) {
+	lexical := []Document{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	vector := []Document{{ID: "c"}, {ID: "d"}, {ID: "a"}}
+
+	fused := fuseRRF(lexical, vector)
+	assert.Equal(t, []string{"a", "c", "b", "d"}, ids(fused))
+	assert.InDelta(t, 1.0/61+1.0/63, fused[0].Score, 1e-12)
+}
+
+func TestHybridRetrieve(t *testing.T) {
+	profiles, err := ranking.NewStore("")
+	require.NoError(t, err)
+	svc := NewServiceWithEngine(&config.Config{}, nil, profiles, NewMemoryEngine())
+	svc.SetEmbedder(NewHashingEmbedder(256))
+	ctx := context.Background()
+	for _, doc := range []Document{
+		{ID: "1", Title: "Go web tutorial", Content: "Build a web server in Go"},
+		{ID: "2", Title: "Elasticsearch guide", Content: "Full text search with elasticsearch"},
+		{ID: "3", Title: "Web design", Content: "CSS layout tips for the web"},
+	} {
+		doc := doc
+		require.NoError(t, svc.IndexDocument(ctx, &doc))
+		require.NotEmpty(t, doc.Embedding)
+	}
+
+	// 没有任何文本命中，向量检索仍能召回
+	result, err := svc.Retrieve(ctx, "webservers", 1, 2, SearchOptions{})
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Hits)
+	assert.Equal(t, "1", result.Hits[0].ID)
+	assert.Nil(t, result.Hits[0].Embedding, "vectors are not returned")
+
+	// 文本与向量均命中的文档排在前面
+	result, err = svc.Retrieve(ctx, "go web", 1, 2, SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1", result.Hits[0].ID)
+	assert.Len(t, result.Hits, 2)
+}
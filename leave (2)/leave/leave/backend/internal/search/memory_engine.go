@@ -0,0 +1,427 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"search-engine-backend/internal/ranking"
+)
+
+// BM25 参数，与 ES 默认值一致
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// MemoryEngine is an in-process Engine over a small document set. It
+// follows the ES query semantics closely enough for tests and offline
+// relevance evaluation: per-field BM25 with best-field scoring, operator
+// and minimum_should_match, AUTO fuzziness, phrase proximity, freshness and
+// authority boosts. Text is analyzed like the ES standard analyzer: words
+// are lowercased and CJK characters become single-character tokens.
+type MemoryEngine struct {
+	mu       sync.RWMutex
+	docs     map[string]*memDoc
+	fieldLen map[string]int // 字段 -> 全部文档的 token 总数
+	now      func() time.Time
+}
+
+type memDoc struct {
+	doc    Document
+	fields map[string][]string // 字段 -> token 序列
+	vector []float32
+}
+
+func NewMemoryEngine() *MemoryEngine {
+	return &MemoryEngine{
+		docs:     make(map[string]*memDoc),
+		fieldLen: make(map[string]int),
+		now:      time.Now,
+	}
+}
+
+// analyze splits text into lowercase tokens; each CJK character is a token.
+func analyze(text string) []string {
+	var tokens []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			tokens = append(tokens, string(cur))
+			cur = cur[:0]
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			cur = append(cur, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+func fieldText(doc *Document, field string) string {
+	switch field {
+	case "title":
+		return doc.Title
+	case "content":
+		return doc.Content
+	case "url":
+		return doc.URL
+	}
+	return ""
+}
+
+func (e *MemoryEngine) Index(ctx context.Context, doc *Document) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if old, ok := e.docs[doc.ID]; ok {
+		for field, tokens := range old.fields {
+			e.fieldLen[field] -= len(tokens)
+		}
+	}
+	// 向量单独保存，返回的文档不携带 embedding
+	d := &memDoc{doc: *doc, fields: make(map[string][]string), vector: doc.Embedding}
+	d.doc.Embedding = nil
+	for _, field := range []string{"title", "content", "url"} {
+		tokens := analyze(fieldText(doc, field))
+		d.fields[field] = tokens
+		e.fieldLen[field] += len(tokens)
+	}
+	e.docs[doc.ID] = d
+	return nil
+}
+
+func (e *MemoryEngine) Documents(ctx context.Context, ids []string) ([]Document, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var docs []Document
+	for _, id := range ids {
+		if d, ok := e.docs[id]; ok {
+			docs = append(docs, d.doc)
+		}
+	}
+	return docs, nil
+}
+
+// KNN returns the k documents whose embeddings are most similar to vector
+// by cosine similarity. Documents without an embedding are skipped.
+func (e *MemoryEngine) KNN(ctx context.Context, vector []float32, k int) (*SearchResult, error) {
+	start := time.Now()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var hits []Document
+	for _, d := range e.docs {
+		if len(d.vector) == 0 {
+			continue
+		}
+		doc := d.doc
+		doc.Score = cosine(vector, d.vector)
+		hits = append(hits, doc)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return &SearchResult{
+		Total: int64(len(hits)),
+		Hits:  hits,
+		Took:  int(time.Since(start).Milliseconds()),
+	}, nil
+}
+
+// Len returns the number of indexed documents.
+func (e *MemoryEngine) Len() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.docs)
+}
+
+func (e *MemoryEngine) Search(ctx context.Context, q Query) (*SearchResult, error) {
+	start := time.Now()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	phraseTerms := analyze(q.Text)
+	terms := uniqueStrings(phraseTerms)
+	result := &SearchResult{}
+	if len(terms) == 0 {
+		return result, nil
+	}
+	required := requiredMatches(len(terms), q.Match)
+	fuzzy := strings.EqualFold(q.Match.Fuzziness, "auto")
+
+	// 第一遍：统计每个字段中每个词的 tf 与 df
+	type fieldStats struct {
+		tf      []int
+		matched int
+	}
+	stats := make(map[string]map[string]*fieldStats, len(e.docs))
+	df := make(map[string][]int)
+	for field := range q.Profile.Fields {
+		df[field] = make([]int, len(terms))
+	}
+	for id, d := range e.docs {
+		perField := make(map[string]*fieldStats)
+		for field := range q.Profile.Fields {
+			fs := &fieldStats{tf: make([]int, len(terms))}
+			for i, term := range terms {
+				for _, tok := range d.fields[field] {
+					if tok == term || (fuzzy && editDistance(tok, term) <= autoFuzziness(term)) {
+						fs.tf[i]++
+					}
+				}
+				if fs.tf[i] > 0 {
+					fs.matched++
+					df[field][i]++
+				}
+			}
+			perField[field] = fs
+		}
+		stats[id] = perField
+	}
+
+	// 第二遍：best_fields 打分，只有满足匹配数要求的字段参与
+	n := float64(len(e.docs))
+	var hits []Document
+	for id, d := range e.docs {
+		best, phrase := 0.0, 0.0
+		matched := false
+		for field, weight := range q.Profile.Fields {
+			fs := stats[id][field]
+			avgLen := float64(e.fieldLen[field]) / n
+			docLen := float64(len(d.fields[field]))
+			score := 0.0
+			for i, tf := range fs.tf {
+				if tf == 0 {
+					continue
+				}
+				idf := math.Log(1 + (n-float64(df[field][i])+0.5)/(float64(df[field][i])+0.5))
+				norm := 1 - bm25B
+				if avgLen > 0 {
+					norm += bm25B * docLen / avgLen
+				}
+				score += idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+			}
+			score *= weight
+			if fs.matched >= required {
+				matched = true
+				best = math.Max(best, score)
+			}
+			if q.Profile.PhraseBoost > 0 && phraseMatch(d.fields[field], phraseTerms, q.Profile.PhraseSlop) {
+				phrase = math.Max(phrase, score*q.Profile.PhraseBoost)
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		doc := d.doc
+		doc.Score = (best + phrase) * e.boostFactor(&doc, q.Profile)
+		hits = append(hits, doc)
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	result.Total = int64(len(hits))
+	from := q.From
+	if from > len(hits) {
+		from = len(hits)
+	}
+	to := from + q.Size
+	if to > len(hits) {
+		to = len(hits)
+	}
+	result.Hits = hits[from:to]
+	result.Took = int(time.Since(start).Milliseconds())
+	return result, nil
+}
+
+// boostFactor mirrors the function_score built by buildQuery:
+// 1 + Σ weight_i * f_i.
+func (e *MemoryEngine) boostFactor(doc *Document, profile ranking.Profile) float64 {
+	factor := 1.0
+	if f := profile.Freshness; f != nil && f.Weight > 0 && !doc.Timestamp.IsZero() {
+		if scale, ok := parseESDuration(f.Scale); ok && scale > 0 && f.Decay > 0 && f.Decay < 1 {
+			dist := math.Abs(e.now().Sub(doc.Timestamp).Seconds())
+			s := scale.Seconds()
+			factor += f.Weight * math.Exp(math.Log(f.Decay)*dist*dist/(s*s))
+		}
+	}
+	if profile.AuthorityWeight > 0 {
+		factor += profile.AuthorityWeight * math.Log10(1+doc.Authority)
+	}
+	return factor
+}
+
+// CorrectSpelling replaces unknown single-token words with the most
+// frequent indexed term within two edits, like the ES term suggester
+// defaults (max_edits 2, min_word_length 4).
+func (e *MemoryEngine) CorrectSpelling(ctx context.Context, text string) (string, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	freq := make(map[string]int)
+	for _, d := range e.docs {
+		for _, field := range []string{"title", "content"} {
+			for _, tok := range uniqueStrings(d.fields[field]) {
+				freq[tok]++
+			}
+		}
+	}
+
+	words := strings.Fields(text)
+	changed := false
+	for i, w := range words {
+		tokens := analyze(w)
+		if len(tokens) != 1 || freq[tokens[0]] > 0 || len([]rune(tokens[0])) < 4 {
+			continue
+		}
+		term := tokens[0]
+		best, bestDist, bestFreq := "", 3, 0
+		for tok, f := range freq {
+			d := editDistance(tok, term)
+			if d < bestDist || (d == bestDist && (f > bestFreq || (f == bestFreq && tok < best))) {
+				best, bestDist, bestFreq = tok, d, f
+			}
+		}
+		if best != "" {
+			words[i] = best
+			changed = true
+		}
+	}
+	if !changed {
+		return "", nil
+	}
+	return strings.Join(words, " "), nil
+}
+
+// requiredMatches returns how many distinct terms a field must contain.
+func requiredMatches(n int, m MatchOptions) int {
+	required := 1
+	switch msm := m.MinimumShouldMatch; {
+	case msm != "":
+		if strings.HasSuffix(msm, "%") {
+			if p, err := strconv.Atoi(strings.TrimSuffix(msm, "%")); err == nil {
+				required = n * p / 100
+			}
+		} else if v, err := strconv.Atoi(msm); err == nil {
+			required = v
+		}
+	case strings.EqualFold(m.Operator, "and"):
+		required = n
+	}
+	if required < 1 {
+		required = 1
+	}
+	if required > n {
+		required = n
+	}
+	return required
+}
+
+// autoFuzziness follows ES AUTO: 0 edits up to 2 characters, 1 up to 5, else 2.
+func autoFuzziness(term string) int {
+	switch n := len([]rune(term)); {
+	case n <= 2:
+		return 0
+	case n <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// phraseMatch reports whether terms occur in order with at most slop extra
+// positions between the first and last term.
+func phraseMatch(tokens, terms []string, slop int) bool {
+	if len(terms) < 2 {
+		return false
+	}
+	for start, tok := range tokens {
+		if tok != terms[0] {
+			continue
+		}
+		limit := start + len(terms) - 1 + slop
+		next := 1
+		for i := start + 1; i < len(tokens) && i <= limit && next < len(terms); i++ {
+			if tokens[i] == terms[next] {
+				next++
+			}
+		}
+		if next == len(terms) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseESDuration parses ES time units such as "30d", "12h" or "90m".
+func parseESDuration(s string) (time.Duration, bool) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64)
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(days * float64(24*time.Hour)), true
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil
+}
+
+func uniqueStrings(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := make([]string, 0, len(in))
+	for _, s := range in {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
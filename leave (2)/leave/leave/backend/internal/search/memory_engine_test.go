@@ -0,0 +1,103 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"search-engine-backend/internal/config"
+	"search-engine-backend/internal/ranking"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEngine(t *testing.T) *MemoryEngine {
+	e := NewMemoryEngine()
+	docs := []Document{
+		{ID: "1", Title: "Go web tutorial", Content: "Build a web server in Go", URL: "https://go.dev/doc/web"},
+		{ID: "2", Title: "Elasticsearch guide", Content: "Full text search with elasticsearch", URL: "https://elastic.co/guide"},
+		{ID: "3", Title: "搜索引擎原理", Content: "倒排索引与相关性排序", URL: "https://example.cn/search"},
+		{ID: "4", Title: "Web design", Content: "CSS layout tips for the web", URL: "https://example.com/css"},
+	}
+	for i := range docs {
+		require.NoError(t, e.Index(context.Background(), &docs[i]))
+	}
+	return e
+}
+
+func ids(hits []Document) []string {
+	out := make([]string, 0, len(hits))
+	for _, h := range hits {
+		out = append(out, h.ID)
+	}
+	return out
+}
+
+func TestMemoryEngineSearch(t *testing.T) {
+	e := newTestEngine(t)
+	ctx := context.Background()
+	profile := ranking.DefaultProfile()
+
+	and, err := e.Search(ctx, Query{Text: "go web", Match: MatchOptions{Operator: "and"}, Profile: profile, Size: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, ids(and.Hits))
+
+	or, err := e.Search(ctx, Query{Text: "go web", Match: MatchOptions{Operator: "or"}, Profile: profile, Size: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), or.Total)
+	assert.Equal(t, "1", or.Hits[0].ID, "matching both terms ranks first")
+
+	cjk, err := e.Search(ctx, Query{Text: "索引", Match: MatchOptions{Operator: "and"}, Profile: profile, Size: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3"}, ids(cjk.Hits))
+
+	fuzzy, err := e.Search(ctx, Query{Text: "elasticsaerch", Match: MatchOptions{Operator: "or", Fuzziness: "AUTO"}, Profile: profile, Size: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2"}, ids(fuzzy.Hits))
+
+	page, err := e.Search(ctx, Query{Text: "web", Match: MatchOptions{Operator: "or"}, Profile: profile, From: 1, Size: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	assert.Len(t, page.Hits, 1)
+}
+
+func TestMemoryEngineFreshness(t *testing.T) {
+	e := NewMemoryEngine()
+	now := time.Now()
+	e.now = func() time.Time { return now }
+	ctx := context.Background()
+	require.NoError(t, e.Index(ctx, &Document{ID: "old", Title: "release notes", Timestamp: now.AddDate(-1, 0, 0)}))
+	require.NoError(t, e.Index(ctx, &Document{ID: "new", Title: "release notes", Timestamp: now.AddDate(0, 0, -1)}))
+
+	profile := ranking.DefaultProfile()
+	profile.Freshness = &ranking.Freshness{Scale: "30d", Decay: 0.5, Weight: 1}
+	res, err := e.Search(ctx, Query{Text: "release", Match: MatchOptions{Operator: "and"}, Profile: profile, Size: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new", "old"}, ids(res.Hits))
+}
+
+func TestRetrieveRelaxation(t *testing.T) {
+	profiles, err := ranking.NewStore("")
+	require.NoError(t, err)
+	svc := NewServiceWithEngine(&config.Config{}, nil, profiles, newTestEngine(t))
+	ctx := context.Background()
+
+	strict, err := svc.Retrieve(ctx, "go web", 1, 10, SearchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, strict.Relaxation)
+
+	dropped, err := svc.Retrieve(ctx, "server kubernetes docker go", 1, 10, SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, RelaxDropTerms, dropped.Relaxation)
+	assert.Equal(t, "server kubernetes", dropped.RelaxedQuery)
+
+	spelled, err := svc.Retrieve(ctx, "srch", 1, 10, SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, RelaxSpelling, spelled.Relaxation)
+	assert.Equal(t, "search", spelled.RelaxedQuery)
+
+	none, err := svc.Retrieve(ctx, "srch", 1, 10, SearchOptions{Variant: ranking.VariantNoRelaxation})
+	require.NoError(t, err)
+	assert.Zero(t, none.Total)
+}
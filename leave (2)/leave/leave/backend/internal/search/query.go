@@ -0,0 +1,104 @@
+package search
+
+import (
+	"search-engine-backend/internal/ranking"
+)
+
+// strictMatch 首轮查询：排序方案配置了 minimum_should_match 时按其匹配，否则要求所有词命中
+func strictMatch(profile ranking.Profile) MatchOptions {
+	if profile.MinimumShouldMatch != "" {
+		return MatchOptions{Operator: "or", MinimumShouldMatch: profile.MinimumShouldMatch}
+	}
+	return MatchOptions{Operator: "and"}
+}
+
+func buildSearchBody(q Query) map[string]interface{} {
+	return map[string]interface{}{
+		"from":  q.From,
+		"size":  q.Size,
+		"query": buildQuery(q.Text, q.Match, q.Profile),
+		// 向量只用于 kNN，不随结果返回
+		"_source": map[string]interface{}{"excludes": []string{"embedding"}},
+		"highlight": map[string]interface{}{
+			"fields": map[string]interface{}{
+				"title":   map[string]interface{}{},
+				"content": map[string]interface{}{},
+			},
+		},
+	}
+}
+
+// buildQuery combines the match clause with the profile's phrase,
+// freshness and authority signals.
+func buildQuery(text string, opts MatchOptions, profile ranking.Profile) map[string]interface{} {
+	fields := profile.FieldList()
+	match := map[string]interface{}{
+		"query":    text,
+		"fields":   fields,
+		"operator": opts.Operator,
+	}
+	if opts.MinimumShouldMatch != "" {
+		match["minimum_should_match"] = opts.MinimumShouldMatch
+	}
+	if opts.Fuzziness != "" {
+		match["fuzziness"] = opts.Fuzziness
+	}
+	query := map[string]interface{}{"multi_match": match}
+
+	// 短语邻近：词序接近的文档额外加分
+	if profile.PhraseBoost > 0 {
+		query = map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": []interface{}{query},
+				"should": []interface{}{
+					map[string]interface{}{
+						"multi_match": map[string]interface{}{
+							"query":  text,
+							"fields": fields,
+							"type":   "phrase",
+							"slop":   profile.PhraseSlop,
+							"boost":  profile.PhraseBoost,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	// 时效与权威度以乘法方式作用于文本得分：score * (1 + Σ weight_i * f_i)
+	var functions []interface{}
+	if f := profile.Freshness; f != nil && f.Weight > 0 {
+		functions = append(functions, map[string]interface{}{
+			"gauss": map[string]interface{}{
+				"timestamp": map[string]interface{}{
+					"origin": "now",
+					"scale":  f.Scale,
+					"decay":  f.Decay,
+				},
+			},
+			"weight": f.Weight,
+		})
+	}
+	if profile.AuthorityWeight > 0 {
+		functions = append(functions, map[string]interface{}{
+			"field_value_factor": map[string]interface{}{
+				"field":    "authority",
+				"modifier": "log1p",
+				"missing":  0,
+			},
+			"weight": profile.AuthorityWeight,
+		})
+	}
+	if len(functions) == 0 {
+		return query
+	}
+	functions = append(functions, map[string]interface{}{"weight": 1})
+	return map[string]interface{}{
+		"function_score": map[string]interface{}{
+			"query":      query,
+			"functions":  functions,
+			"score_mode": "sum",
+			"boost_mode": "multiply",
+		},
+	}
+}
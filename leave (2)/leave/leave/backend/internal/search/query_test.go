@@ -0,0 +1,55 @@
+package search
+
+import (
+	"testing"
+
+	"search-engine-backend/internal/ranking"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSearchBody(t *testing.T) {
+	body := buildSearchBody(Query{
+		Text:    "go web",
+		Match:   MatchOptions{Operator: "or", MinimumShouldMatch: "75%", Fuzziness: "AUTO"},
+		Profile: ranking.DefaultProfile(),
+		From:    20,
+		Size:    10,
+	})
+	match := body["query"].(map[string]interface{})["multi_match"].(map[string]interface{})
+
+	assert.Equal(t, 20, body["from"])
+	assert.Equal(t, "or", match["operator"])
+	assert.Equal(t, "75%", match["minimum_should_match"])
+	assert.Equal(t, "AUTO", match["fuzziness"])
+
+	assert.Equal(t, []string{"content", "title^3"}, match["fields"])
+
+	strict := buildQuery("go web", strictMatch(ranking.DefaultProfile()), ranking.DefaultProfile())["multi_match"].(map[string]interface{})
+	assert.Equal(t, "and", strict["operator"])
+	assert.NotContains(t, strict, "fuzziness")
+}
+
+func TestBuildQueryWithProfile(t *testing.T) {
+	profile := ranking.Profile{
+		Name:            "fresh",
+		Fields:          map[string]float64{"title": 2, "content": 1},
+		PhraseBoost:     2,
+		PhraseSlop:      3,
+		Freshness:       &ranking.Freshness{Scale: "30d", Decay: 0.5, Weight: 0.5},
+		AuthorityWeight: 0.2,
+	}
+	q := buildQuery("go web", strictMatch(profile), profile)
+
+	fs := q["function_score"].(map[string]interface{})
+	assert.Len(t, fs["functions"], 3, "freshness, authority and the constant baseline")
+	assert.Equal(t, "multiply", fs["boost_mode"])
+
+	phrase := fs["query"].(map[string]interface{})["bool"].(map[string]interface{})["should"].([]interface{})[0]
+	mm := phrase.(map[string]interface{})["multi_match"].(map[string]interface{})
+	assert.Equal(t, "phrase", mm["type"])
+	assert.Equal(t, 3, mm["slop"])
+
+	profile.MinimumShouldMatch = "2"
+	assert.Equal(t, MatchOptions{Operator: "or", MinimumShouldMatch: "2"}, strictMatch(profile))
+}
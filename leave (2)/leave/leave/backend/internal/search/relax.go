@@ -0,0 +1,110 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"search-engine-backend/internal/ranking"
+)
+
+// Relaxation names reported in SearchResult.Relaxation.
+const (
+	RelaxOr        = "or"
+	RelaxFuzzy     = "fuzzy"
+	RelaxDropTerms = "drop_terms"
+	RelaxSpelling  = "spelling"
+)
+
+// relaxation 一个放宽步骤：可选地改写分词结果，并使用更宽松的匹配参数
+type relaxation struct {
+	name    string
+	rewrite func(words []string) []string
+	opts    MatchOptions
+}
+
+// relaxations 按从严到宽的顺序排列；拼写纠错需要额外请求 ES，放在最后单独处理
+var relaxations = []relaxation{
+	{name: RelaxOr, opts: MatchOptions{Operator: "or", MinimumShouldMatch: "75%"}},
+	{name: RelaxFuzzy, opts: MatchOptions{Operator: "or", MinimumShouldMatch: "75%", Fuzziness: "AUTO"}},
+	{name: RelaxDropTerms, rewrite: dropLeastImportant, opts: MatchOptions{Operator: "or", Fuzziness: "AUTO"}},
+}
+
+// searchWithRelaxation runs the strict query and, while it finds nothing,
+// the relaxations in order. The first step with hits wins.
+func (s *Service) searchWithRelaxation(ctx context.Context, words []string, profile ranking.Profile, from, size int) (*SearchResult, error) {
+	text := strings.Join(words, " ")
+	result, err := s.runQuery(ctx, text, strictMatch(profile), profile, from, size)
+	if err != nil || result.Total > 0 {
+		return result, err
+	}
+
+	tried := map[string]bool{}
+	for _, r := range relaxations {
+		relaxedWords := words
+		if r.rewrite != nil {
+			relaxedWords = r.rewrite(words)
+		}
+		relaxedText := strings.Join(relaxedWords, " ")
+		key := r.opts.Operator + r.opts.MinimumShouldMatch + r.opts.Fuzziness + "|" + relaxedText
+		if relaxedText == "" || tried[key] {
+			continue
+		}
+		tried[key] = true
+
+		relaxed, err := s.runQuery(ctx, relaxedText, r.opts, profile, from, size)
+		if err != nil {
+			return nil, err
+		}
+		if relaxed.Total > 0 {
+			relaxed.Relaxation = r.name
+			relaxed.RelaxedQuery = relaxedText
+			return relaxed, nil
+		}
+	}
+
+	corrected, err := s.engine.CorrectSpelling(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	if corrected != "" && corrected != text {
+		relaxed, err := s.runQuery(ctx, corrected, strictMatch(profile), profile, from, size)
+		if err != nil {
+			return nil, err
+		}
+		if relaxed.Total > 0 {
+			relaxed.Relaxation = RelaxSpelling
+			relaxed.RelaxedQuery = corrected
+			return relaxed, nil
+		}
+	}
+
+	return result, nil
+}
+
+// dropLeastImportant keeps the longer half of the terms. Without corpus
+// statistics, term length is a cheap proxy for specificity: short terms
+// tend to be particles and common words.
+func dropLeastImportant(words []string) []string {
+	if len(words) < 2 {
+		return words
+	}
+	keep := (len(words) + 1) / 2
+
+	idx := make([]int, len(words))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		return utf8.RuneCountInString(words[idx[a]]) > utf8.RuneCountInString(words[idx[b]])
+	})
+	kept := idx[:keep]
+	sort.Ints(kept)
+
+	out := make([]string, 0, keep)
+	for _, i := range kept {
+		out = append(out, words[i])
+	}
+	return out
+}
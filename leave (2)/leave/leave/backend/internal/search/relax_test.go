@@ -0,0 +1,67 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"search-engine-backend/internal/cache"
+	"search-engine-backend/internal/config"
+	"search-engine-backend/internal/ranking"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDropLeastImportant(t *testing.T) {
+	assert.Equal(t, []string{"golang"}, dropLeastImportant([]string{"golang"}))
+	assert.Equal(t, []string{"elasticsearch", "tutorial"}, dropLeastImportant([]string{"the", "elasticsearch", "of", "tutorial"}))
+	assert.Equal(t, []string{"搜索引擎", "原理"}, dropLeastImportant([]string{"搜索引擎", "的", "原理"}))
+}
+
+func TestSearchRelaxation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := cache.NewCacheService(mr.Addr(), "", cache.Options{Namespace: "test:"})
+	t.Cleanup(func() { c.Close() })
+	profiles, err := ranking.NewStore("")
+	require.NoError(t, err)
+	svc := NewServiceWithEngine(&config.Config{}, c, profiles, NewMemoryEngine())
+	ctx := context.Background()
+	require.NoError(t, svc.IndexDocument(ctx, &Document{ID: "1", Title: "golang concurrency tutorial"}))
+	require.NoError(t, svc.IndexDocument(ctx, &Document{ID: "2", Title: "python tutorial"}))
+
+	search := func(query string, opts SearchOptions) *SearchResult {
+		t.Helper()
+		result, err := svc.Search(ctx, query, 1, 10, opts)
+		require.NoError(t, err)
+		return result
+	}
+
+	// 严格查询有结果时不放宽，即使 OR 能匹配更多文档
+	result := search("python tutorial", SearchOptions{})
+	assert.Equal(t, []string{"2"}, ids(result.Hits))
+	assert.Empty(t, result.Relaxation)
+	assert.Empty(t, result.RelaxedQuery)
+
+	result = search("python concurrency", SearchOptions{})
+	assert.ElementsMatch(t, []string{"1", "2"}, ids(result.Hits))
+	assert.Equal(t, RelaxOr, result.Relaxation)
+	assert.Equal(t, "python concurrency", result.RelaxedQuery)
+
+	result = search("concurency", SearchOptions{})
+	assert.Equal(t, []string{"1"}, ids(result.Hits))
+	assert.Equal(t, RelaxFuzzy, result.Relaxation)
+
+	// 四个词中至少要匹配三个时仍无结果，去掉较短的 yy、zz 后匹配
+	result = search("xx yy zz concurrency", SearchOptions{})
+	assert.Equal(t, []string{"1"}, ids(result.Hits))
+	assert.Equal(t, RelaxDropTerms, result.Relaxation)
+	assert.Equal(t, "xx concurrency", result.RelaxedQuery, "reports the terms that were kept")
+
+	// 关闭放宽的实验组使用不同的缓存 key，不会读到放宽后的缓存结果
+	result = search("python concurrency", SearchOptions{Variant: ranking.VariantNoRelaxation})
+	assert.Empty(t, result.Hits)
+	assert.Empty(t, result.Relaxation)
+	result = search("python concurrency", SearchOptions{})
+	assert.Equal(t, RelaxOr, result.Relaxation, "the relaxed result is still cached for the default pipeline")
+}
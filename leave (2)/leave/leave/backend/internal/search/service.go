@@ -1,24 +1,37 @@
 package search
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
+	"search-engine-backend/internal/cache"
 	"search-engine-backend/internal/config"
-
-	"github.com/elastic/go-elasticsearch/v8"
-	"github.com/elastic/go-elasticsearch/v8/esapi"
-	"github.com/go-redis/redis/v8"
+	"search-engine-backend/internal/ranking"
 )
 
 type Service struct {
-	esClient    *elasticsearch.Client
-	redisClient *redis.Client
-	cfg         *config.Config
+	engine     Engine
+	cache      *cache.CacheService
+	stale      *cache.CacheService
+	cfg        *config.Config
+	profiles   *ranking.Store
+	curator    Curator
+	embedder   Embedder
+	classifier Classifier
+}
+
+// SearchOptions 可选的搜索参数
+type SearchOptions struct {
+	// Profile 排序方案名称，空表示默认方案
+	Profile string
+	// Variant 检索管线变体，见 ranking.VariantNoRelaxation
+	Variant string
+	// Session 会话标识，非空时首页搜索计入会话序列，用于挖掘相关搜索
+	Session string
 }
 
 type SearchResult struct {
@@ -26,6 +39,13 @@ type SearchResult struct {
 	Hits        []Document `json:"hits"`
 	Took        int        `json:"took"`
 	Suggestions []string   `json:"suggestions"`
+	// Degraded 表示 ES 不可用，结果来自过期缓存
+	Degraded bool `json:"degraded"`
+	// Relaxation 为严格查询无结果时实际采用的放宽方式，RelaxedQuery 为对应的查询词
+	Relaxation   string `json:"relaxation,omitempty"`
+	RelaxedQuery string `json:"relaxed_query,omitempty"`
+	// Curated 表示结果经过运营置顶或隐藏
+	Curated bool `json:"curated,omitempty"`
 }
 
 type Document struct {
@@ -35,157 +55,242 @@ type Document struct {
 	URL       string    `json:"url"`
 	Score     float64   `json:"score"`
 	Timestamp time.Time `json:"timestamp"`
+	// Authority 域名权威度，供排序方案的 authority_weight 使用
+	Authority float64 `json:"authority,omitempty"`
+	// Pinned 表示文档由运营固定在当前位置
+	Pinned bool `json:"pinned,omitempty"`
+	// Embedding 索引时由 Embedder 生成的向量，不在搜索结果中返回
+	Embedding []float32 `json:"embedding,omitempty"`
+	// Category 索引时由 Classifier 判定的内容分类 (如 adult、gambling)，正常内容为空
+	Category           string  `json:"category,omitempty"`
+	CategoryConfidence float64 `json:"category_confidence,omitempty"`
+	// Classified 表示索引时已经过 Classifier 判定，Category 为空即判为正常内容
+	Classified bool `json:"classified,omitempty"`
 }
 
-func NewService(cfg *config.Config) (*Service, error) {
-	esCfg := elasticsearch.Config{
-		Addresses: []string{cfg.ElasticsearchURL},
-	}
-	esClient, err := elasticsearch.NewClient(esCfg)
+// Classifier assigns a content category to documents at index time. An
+// empty category means the document looks safe.
+type Classifier interface {
+	Classify(title, content string) (category string, confidence float64)
+}
+
+// SetClassifier makes IndexDocument classify documents that have no
+// category yet.
+func (s *Service) SetClassifier(c Classifier) {
+	s.classifier = c
+}
+
+func NewService(cfg *config.Config, cacheSvc *cache.CacheService, profiles *ranking.Store) (*Service, error) {
+	engine, err := NewESEngine(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("error creating elasticsearch client: %s", err)
+		return nil, err
 	}
+	return NewServiceWithEngine(cfg, cacheSvc, profiles, engine), nil
+}
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     cfg.RedisAddr,
-		Password: cfg.RedisPassword,
-		DB:       0,
-	})
+// NewServiceWithEngine builds a Service on top of any Engine. cacheSvc may
+// be nil for offline tools that only call Retrieve.
+func NewServiceWithEngine(cfg *config.Config, cacheSvc *cache.CacheService, profiles *ranking.Store, engine Engine) *Service {
+	s := &Service{
+		engine:   engine,
+		cache:    cacheSvc,
+		cfg:      cfg,
+		profiles: profiles,
+	}
+	if cacheSvc != nil {
+		s.stale = cacheSvc.Sub("stale:")
+		// 排序方案变更后旧的缓存结果不再有效
+		profiles.OnReload(func() {
+			if err := s.cache.Invalidate(context.Background(), "search:"); err != nil {
+				log.Printf("failed to invalidate search cache after profile reload: %v", err)
+			}
+		})
+	}
+	return s
+}
 
-	return &Service{
-		esClient:    esClient,
-		redisClient: rdb,
-		cfg:         cfg,
-	}, nil
+// Profiles lists the configured ranking profile names.
+func (s *Service) Profiles() []string {
+	return s.profiles.Names()
 }
 
-func (s *Service) Close() {
-	s.redisClient.Close()
+// Assign returns the experiment arm for an anonymous user, if an
+// experiment is running.
+func (s *Service) Assign(anonID string) (ranking.Assignment, bool) {
+	return s.profiles.Assign(anonID)
 }
 
-func (s *Service) Search(ctx context.Context, query string, page, size int) (*SearchResult, error) {
-	// 1. Check Cache
-	cacheKey := fmt.Sprintf("search:%s:%d:%d", query, page, size)
-	val, err := s.redisClient.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var result SearchResult
-		if err := json.Unmarshal([]byte(val), &result); err == nil {
-			return &result, nil
-		}
+// ResultsGeneration returns the generation of the cached search results.
+// It changes whenever the index, the curation rules or the ranking
+// profiles change, so that state derived from results can be dropped.
+func (s *Service) ResultsGeneration(ctx context.Context) int64 {
+	gen, err := s.cache.Generation(ctx, "search:")
+	if err != nil {
+		log.Printf("failed to read search cache generation: %v", err)
 	}
+	return gen
+}
 
-	// 2. Simple Segmentation (Whitespace) - Replacing Jieba to avoid CGO dependency
-	// In a real Windows environment without GCC, pure Go tokenizers like "github.com/wangbin/jiebago"
-	// or "github.com/go-ego/gse" are recommended over CGO-based ones.
-	// For now, we use simple splitting to ensure compilation succeeds.
-	words := strings.Fields(query)
-	finalQuery := strings.Join(words, " ")
-
-	// 3. Build ES Query
-	var buf bytes.Buffer
-	queryMap := map[string]interface{}{
-		"from": (page - 1) * size,
-		"size": size,
-		"query": map[string]interface{}{
-			"multi_match": map[string]interface{}{
-				"query":  finalQuery,
-				"fields": []string{"title^3", "content"},
-			},
-		},
-		"highlight": map[string]interface{}{
-			"fields": map[string]interface{}{
-				"title":   map[string]interface{}{},
-				"content": map[string]interface{}{},
-			},
-		},
-	}
-	if err := json.NewEncoder(&buf).Encode(queryMap); err != nil {
-		return nil, err
-	}
+// CacheStats returns hit/miss counters of the cache layer.
+func (s *Service) CacheStats() cache.Stats {
+	return s.cache.Stats()
+}
 
-	// 4. Execute Search
-	res, err := s.esClient.Search(
-		s.esClient.Search.WithContext(ctx),
-		s.esClient.Search.WithIndex("webpages"),
-		s.esClient.Search.WithBody(&buf),
-		s.esClient.Search.WithTrackTotalHits(true),
-	)
+func (s *Service) Search(ctx context.Context, query string, page, size int, opts SearchOptions) (*SearchResult, error) {
+	profile, err := s.profiles.Get(opts.Profile)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
 
-	if res.IsError() {
-		return nil, fmt.Errorf("search request failed: %s", res.String())
+	if opts.Session != "" && page == 1 {
+		if err := s.cache.RecordSessionQuery(ctx, opts.Session, NormalizeQuery(query)); err != nil {
+			log.Printf("failed to record session query: %v", err)
+		}
 	}
 
-	// 5. Parse Response
-	var r map[string]interface{}
-	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+	// 缓存 key 带上代数，索引写入、运营规则或排序方案变更后所有实例都不再读取旧结果；
+	// 过期缓存层不带代数，ES 不可用时仍可降级使用
+	key := fmt.Sprintf("%s:%s:%s:%d:%d", profile.Name, opts.Variant, query, page, size)
+	gen := s.ResultsGeneration(ctx)
+	cacheKey := fmt.Sprintf("search:%d:%s", gen, key)
+	staleKey := "search:" + key
+	result, err := cache.GetOrSet(ctx, s.cache, cacheKey, 5*time.Minute, func() (*SearchResult, error) {
+		// 有运营干预时从头取到本页的自然结果，去掉置顶和隐藏的文档后再分页
+		from, n := (page-1)*size, size
+		c, curated := s.curation(query)
+		if curated {
+			from, n = 0, c.window(page, size)
+		}
+		result, err := s.retrieve(ctx, query, profile, opts.Variant, from, n)
+		if err != nil {
+			return nil, err
+		}
+		if curated {
+			s.curate(ctx, query, c, result, page, size)
+		}
+		// 同时写入长效的过期缓存层，供 ES 不可用时降级使用
+		if err := cache.Set(ctx, s.stale, staleKey, result, s.cfg.StaleCacheTTL); err != nil {
+			log.Printf("failed to write stale cache for %s: %v", staleKey, err)
+		}
+		return result, nil
+	})
+	if err != nil {
+		// ES 熔断或超时时，退回到过期缓存
+		if errors.Is(err, ErrCircuitOpen) || errors.Is(err, errUnavailable) {
+			if stale, ok, _ := cache.Get[*SearchResult](ctx, s.stale, staleKey); ok {
+				stale.Degraded = true
+				return stale, nil
+			}
+		}
 		return nil, err
 	}
+	return result, nil
+}
 
-	hits := r["hits"].(map[string]interface{})
-	total := int64(hits["total"].(map[string]interface{})["value"].(float64))
-	took := int(r["took"].(float64))
-
-	var documents []Document
-	for _, hit := range hits["hits"].([]interface{}) {
-		h := hit.(map[string]interface{})
-		source := h["_source"].(map[string]interface{})
-		doc := Document{
-			ID:      h["_id"].(string),
-			Title:   source["title"].(string),
-			Content: source["content"].(string),
-			URL:     source["url"].(string),
-			Score:   h["_score"].(float64),
-		}
-		documents = append(documents, doc)
+// Retrieve runs the retrieval pipeline without the cache layer.
+func (s *Service) Retrieve(ctx context.Context, query string, page, size int, opts SearchOptions) (*SearchResult, error) {
+	profile, err := s.profiles.Get(opts.Profile)
+	if err != nil {
+		return nil, err
 	}
+	return s.retrieve(ctx, query, profile, opts.Variant, (page-1)*size, size)
+}
 
-	result := &SearchResult{
-		Total:       total,
-		Hits:        documents,
-		Took:        took,
-		Suggestions: s.getSuggestions(query),
+// retrieve runs the query against the engine for size hits starting at from,
+// relaxing it step by step when the strict query finds nothing.
+func (s *Service) retrieve(ctx context.Context, query string, profile ranking.Profile, variant string, from, size int) (*SearchResult, error) {
+	// 2. Simple Segmentation (Whitespace) - Replacing Jieba to avoid CGO dependency
+	// In a real Windows environment without GCC, pure Go tokenizers like "github.com/wangbin/jiebago"
+	// or "github.com/go-ego/gse" are recommended over CGO-based ones.
+	// For now, we use simple splitting to ensure compilation succeeds.
+	words := strings.Fields(query)
+
+	// 3. Build & Execute Query, 无结果时逐级放宽
+	// 混合检索时取前 from+size 条文本结果，与向量结果融合后再分页
+	lexFrom, lexSize := from, size
+	if s.embedder != nil {
+		lexFrom, lexSize = 0, from+size
+	}
+	var result *SearchResult
+	var err error
+	if variant == ranking.VariantNoRelaxation {
+		result, err = s.runQuery(ctx, strings.Join(words, " "), strictMatch(profile), profile, lexFrom, lexSize)
+	} else {
+		result, err = s.searchWithRelaxation(ctx, words, profile, lexFrom, lexSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.embedder != nil {
+		s.fuseVectorHits(ctx, query, result, from, size)
 	}
+	result.Suggestions = s.relatedSearches(ctx, query)
+	return result, nil
+}
 
-	// 6. Cache Result (Async)
-	go func() {
-		data, _ := json.Marshal(result)
-		s.redisClient.Set(context.Background(), cacheKey, data, 5*time.Minute)
-	}()
+func (s *Service) runQuery(ctx context.Context, text string, opts MatchOptions, profile ranking.Profile, from, size int) (*SearchResult, error) {
+	return s.engine.Search(ctx, Query{
+		Text:    text,
+		Match:   opts,
+		Profile: profile,
+		From:    from,
+		Size:    size,
+	})
+}
 
-	return result, nil
+// NormalizeQuery lowercases a query and collapses whitespace so that
+// equivalent queries are counted together.
+func NormalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
 }
 
-func (s *Service) getSuggestions(query string) []string {
-	// Simplified suggestion logic
-	// In production, this would query ES completion suggester or Redis sorted sets
-	return []string{query + " tutorial", query + " example", query + " docs"}
+// RecordQuery counts a successful search towards the trending lists.
+func (s *Service) RecordQuery(ctx context.Context, query string) error {
+	return s.cache.AddHotQuery(ctx, NormalizeQuery(query))
 }
 
-func (s *Service) IndexDocument(ctx context.Context, doc *Document) error {
-	data, err := json.Marshal(doc)
-	if err != nil {
-		return err
-	}
+// Trending returns the top n queries of a trending window ("hour" or "day").
+func (s *Service) Trending(ctx context.Context, window string, n int64) ([]cache.HotQuery, error) {
+	return s.cache.GetHotQueries(ctx, window, n)
+}
 
-	req := esapi.IndexRequest{
-		Index:      "webpages",
-		DocumentID: doc.ID,
-		Body:       bytes.NewReader(data),
-		Refresh:    "true",
+// relatedSearches returns the related searches precomputed by the
+// related.Miner job, or an empty list.
+func (s *Service) relatedSearches(ctx context.Context, query string) []string {
+	if s.cache == nil {
+		return []string{}
+	}
+	related, err := s.cache.Related(ctx, NormalizeQuery(query))
+	if err != nil || related == nil {
+		return []string{}
 	}
+	return related
+}
 
-	res, err := req.Do(ctx, s.esClient)
-	if err != nil {
+func (s *Service) IndexDocument(ctx context.Context, doc *Document) error {
+	if s.classifier != nil && doc.Category == "" {
+		doc.Category, doc.CategoryConfidence = s.classifier.Classify(doc.Title, doc.Content)
+		doc.Classified = true
+		if doc.Category == "" {
+			doc.CategoryConfidence = 0
+		}
+	}
+	if s.embedder != nil && len(doc.Embedding) == 0 {
+		vec, err := s.embedder.Embed(ctx, doc.Title+"\n"+doc.Content)
+		if err != nil {
+			return err
+		}
+		doc.Embedding = vec
+	}
+	if err := s.engine.Index(ctx, doc); err != nil {
 		return err
 	}
-	defer res.Body.Close()
 
-	if res.IsError() {
-		return fmt.Errorf("error indexing document: %s", res.String())
+	// 索引变更后使所有实例的搜索缓存失效
+	if s.cache != nil {
+		if err := s.cache.Invalidate(ctx, "search:"); err != nil {
+			log.Printf("failed to publish cache invalidation: %v", err)
+		}
 	}
 	return nil
 }
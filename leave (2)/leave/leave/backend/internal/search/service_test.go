@@ -1,7 +1,17 @@
 package search
 
 import (
+	"context"
 	"testing"
+	"time"
+
+	"search-engine-backend/internal/cache"
+	"search-engine-backend/internal/config"
+	"search-engine-backend/internal/ranking"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func BenchmarkService_Search(b *testing.B) {
@@ -13,3 +23,30 @@ func BenchmarkService_Search(b *testing.B) {
 		}
 	})
 }
+
+func TestSearchCacheInvalidatedByIndex(t *testing.T) {
+	mr := miniredis.RunT(t)
+	profiles, err := ranking.NewStore("")
+	require.NoError(t, err)
+	engine := NewMemoryEngine()
+	// 两个实例共享 Redis 和索引，各自有本地缓存
+	newInstance := func() *Service {
+		c := cache.NewCacheService(mr.Addr(), "", cache.Options{Namespace: "test:", LocalSize: 100, LocalTTL: time.Minute})
+		t.Cleanup(func() { c.Close() })
+		return NewServiceWithEngine(&config.Config{StaleCacheTTL: time.Hour}, c, profiles, engine)
+	}
+	a, b := newInstance(), newInstance()
+	ctx := context.Background()
+
+	require.NoError(t, a.IndexDocument(ctx, &Document{ID: "1", Title: "Go web tutorial"}))
+	result, err := b.Search(ctx, "go", 1, 10, SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, ids(result.Hits))
+
+	// 另一个实例写入后，收到失效消息即不再读取旧的缓存结果
+	require.NoError(t, a.IndexDocument(ctx, &Document{ID: "2", Title: "Go concurrency"}))
+	assert.Eventually(t, func() bool {
+		result, err := b.Search(ctx, "go", 1, 10, SearchOptions{})
+		return err == nil && len(result.Hits) == 2
+	}, time.Second, 10*time.Millisecond)
+}
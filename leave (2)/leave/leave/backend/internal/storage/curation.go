@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a record does not exist.
+var ErrNotFound = errors.New("record not found")
+
+// 查询匹配方式
+const (
+	MatchExact    = "exact"
+	MatchPrefix   = "prefix"
+	MatchContains = "contains"
+)
+
+// CurationRule 针对某个查询的人工干预：将指定文档固定在某个位置，或隐藏文档
+type CurationRule struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// Pattern 归一化后的查询词，按 Match 方式匹配
+	Pattern   string    `gorm:"index;not null" json:"pattern"`
+	Match     string    `gorm:"not null;default:'exact'" json:"match"`
+	Pins      []Pin     `gorm:"serializer:json" json:"pins"`
+	Hidden    []string  `gorm:"serializer:json" json:"hidden"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Pin 固定位置，Position 从 1 开始计
+type Pin struct {
+	DocID    string `json:"doc_id"`
+	Position int    `json:"position"`
+}
+
+func (d *DB) ListCurationRules() ([]CurationRule, error) {
+	var rules []CurationRule
+	err := d.db.Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// CurationRulesVersion changes whenever a rule is created, updated or
+// deleted, so other instances can detect changes without loading rules.
+func (d *DB) CurationRulesVersion() (string, error) {
+	var v struct {
+		Count   int64
+		MaxID   uint
+		Updated string
+	}
+	err := d.db.Model(&CurationRule{}).
+		Select("COUNT(*) AS count, COALESCE(MAX(id), 0) AS max_id, COALESCE(MAX(updated_at), '') AS updated").
+		Scan(&v).Error
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d/%d/%s", v.Count, v.MaxID, v.Updated), nil
+}
+
+func (d *DB) GetCurationRule(id uint) (*CurationRule, error) {
+	var rule CurationRule
+	err := d.db.First(&rule, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (d *DB) CreateCurationRule(rule *CurationRule) error {
+	return d.db.Create(rule).Error
+}
+
+// UpdateCurationRule replaces every field of an existing rule.
+func (d *DB) UpdateCurationRule(rule *CurationRule) error {
+	res := d.db.Model(&CurationRule{ID: rule.ID}).
+		Select("Pattern", "Match", "Pins", "Hidden", "Note").
+		Updates(rule)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (d *DB) DeleteCurationRule(id uint) error {
+	res := d.db.Delete(&CurationRule{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -46,7 +46,7 @@ func NewDB(dsn string) (*DB, error) {
 	}
 
 	// Auto Migrate
-	err = db.AutoMigrate(&CrawlTask{}, &PageResult{}, &ErrorLog{})
+	err = db.AutoMigrate(&CrawlTask{}, &PageResult{}, &ErrorLog{}, &QueryLog{}, &ClickLog{}, &CurationRule{}, &FilterRule{}, &FilterAudit{})
 	if err != nil {
 		return nil, err
 	}
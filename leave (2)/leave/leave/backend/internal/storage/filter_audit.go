@@ -0,0 +1,89 @@
+package storage
+
+import "time"
+
+// FilterAudit 一条被过滤结果的审计记录。只追加写入，只由保留期清理删除
+type FilterAudit struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// QueryHash 加盐哈希后的归一化查询词，不保存原文
+	QueryHash  string    `gorm:"index" json:"query_hash"`
+	DocID      string    `gorm:"index" json:"doc_id"`
+	URL        string    `json:"url"`
+	Rule       string    `gorm:"index" json:"rule"`
+	Category   string    `gorm:"index" json:"category"`
+	Region     string    `gorm:"index" json:"region"`
+	SafeSearch string    `json:"safe_search"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// AuditQuery 审计记录查询条件，空字段不限制
+type AuditQuery struct {
+	From, To  time.Time
+	QueryHash string
+	DocID     string
+	Rule      string
+	Category  string
+	Region    string
+	Limit     int
+	Offset    int
+}
+
+// RuleCount 按规则汇总的过滤次数
+type RuleCount struct {
+	Rule     string `json:"rule"`
+	Category string `json:"category"`
+	Count    int64  `json:"count"`
+	Docs     int64  `json:"docs"` // 不同文档数
+}
+
+func (d *DB) SaveFilterAudits(rows []FilterAudit) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return d.db.CreateInBatches(rows, 100).Error
+}
+
+// SearchFilterAudits returns the matching records, newest first, and the
+// total number of matches.
+func (d *DB) SearchFilterAudits(q AuditQuery) ([]FilterAudit, int64, error) {
+	scope := d.db.Model(&FilterAudit{}).Where("created_at >= ? AND created_at < ?", q.From, q.To)
+	for column, value := range map[string]string{
+		"query_hash": q.QueryHash,
+		"doc_id":     q.DocID,
+		"rule":       q.Rule,
+		"category":   q.Category,
+		"region":     q.Region,
+	} {
+		if value != "" {
+			scope = scope.Where(column+" = ?", value)
+		}
+	}
+
+	var total int64
+	if err := scope.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var rows []FilterAudit
+	err := scope.Order("created_at DESC, id DESC").Limit(q.Limit).Offset(q.Offset).Find(&rows).Error
+	return rows, total, err
+}
+
+// FilterAuditsByRule aggregates the records in [from, to) by rule.
+func (d *DB) FilterAuditsByRule(from, to time.Time, limit int) ([]RuleCount, error) {
+	var rows []RuleCount
+	err := d.db.Model(&FilterAudit{}).
+		Select("rule, category, COUNT(*) AS count, COUNT(DISTINCT doc_id) AS docs").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("rule, category").
+		Order("count DESC, rule").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// PurgeFilterAudits deletes records created before cutoff and returns how
+// many were deleted.
+func (d *DB) PurgeFilterAudits(cutoff time.Time) (int64, error) {
+	res := d.db.Where("created_at < ?", cutoff).Delete(&FilterAudit{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 过滤规则类型
+const (
+	FilterRuleDomain   = "domain"    // 屏蔽域名及其子域名
+	FilterRuleKeyword  = "keyword"   // 屏蔽包含关键词的结果和查询
+	FilterRuleAllow    = "allow"     // 域名白名单，优先于屏蔽域名
+	FilterRuleURLRegex = "url_regex" // 正则匹配 URL 路径，如 /casino/
+	FilterRuleWildcard = "wildcard"  // 通配符匹配主机名，如 *.bet*
+	FilterRuleNear     = "near"      // 两个关键词相距不超过 N 个字符，如 "赌场 NEAR/10 注册"
+)
+
+// FilterRule 合规人员维护的屏蔽规则
+type FilterRule struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	Kind     string `gorm:"index;not null" json:"kind"`
+	Value    string `gorm:"not null" json:"value"`
+	Category string `json:"category"`
+	// Regions 生效地区，为空时在所有受限地区生效
+	Regions []string `gorm:"serializer:json" json:"regions"`
+	// Fields 关键词类规则匹配的字段 (title、content、url)，为空时匹配标题和正文
+	Fields    []string   `gorm:"serializer:json" json:"fields,omitempty"`
+	Creator   string     `json:"creator"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Expired reports whether the rule has expired at now.
+func (r FilterRule) Expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+func (d *DB) ListFilterRules() ([]FilterRule, error) {
+	var rules []FilterRule
+	err := d.db.Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// FilterRulesVersion changes whenever a rule is created, updated or
+// deleted, so other instances can detect changes without loading rules.
+func (d *DB) FilterRulesVersion() (string, error) {
+	var v struct {
+		Count   int64
+		MaxID   uint
+		Updated string
+	}
+	err := d.db.Model(&FilterRule{}).
+		Select("COUNT(*) AS count, COALESCE(MAX(id), 0) AS max_id, COALESCE(MAX(updated_at), '') AS updated").
+		Scan(&v).Error
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d/%d/%s", v.Count, v.MaxID, v.Updated), nil
+}
+
+func (d *DB) GetFilterRule(id uint) (*FilterRule, error) {
+	var rule FilterRule
+	err := d.db.First(&rule, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (d *DB) CreateFilterRule(rule *FilterRule) error {
+	return d.db.Create(rule).Error
+}
+
+// UpdateFilterRule replaces every editable field of an existing rule.
+// The creator is kept.
+func (d *DB) UpdateFilterRule(rule *FilterRule) error {
+	res := d.db.Model(&FilterRule{ID: rule.ID}).
+		Select("Kind", "Value", "Category", "Regions", "Fields", "Reason", "ExpiresAt").
+		Updates(rule)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (d *DB) DeleteFilterRule(id uint) error {
+	res := d.db.Delete(&FilterRule{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
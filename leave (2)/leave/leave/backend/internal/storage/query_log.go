@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// QueryLog 一次搜索请求的记录，用于搜索分析
+type QueryLog struct {
+	ID            uint   `gorm:"primaryKey"`
+	SearchID      string `gorm:"index"`
+	Query         string `gorm:"index"` // 归一化后的查询词
+	ResultCount   int64
+	LatencyMs     int64
+	Page          int
+	Restricted    bool // 请求来自受内容管控的地区
+	FilteredCount int
+	ClientID      string    `gorm:"index"` // 匿名化的客户端 ID
+	Experiment    string    `gorm:"index"`
+	Arm           string
+	CreatedAt     time.Time `gorm:"index"`
+}
+
+// ClickLog 搜索结果点击记录，通过 SearchID 关联到 QueryLog
+type ClickLog struct {
+	ID         uint   `gorm:"primaryKey"`
+	SearchID   string `gorm:"index"`
+	DocID      string
+	URL        string
+	Position   int
+	ClientID   string
+	Experiment string `gorm:"index"`
+	Arm        string
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+// ArmReport 实验组指标
+type ArmReport struct {
+	Arm             string  `json:"arm"`
+	Searches        int64   `json:"searches"`
+	ZeroResults     int64   `json:"zero_results"`
+	ZeroResultRate  float64 `json:"zero_result_rate"`
+	ClickedSearches int64   `json:"clicked_searches"`
+	Clicks          int64   `json:"clicks"`
+	CTR             float64 `json:"ctr"`
+}
+
+type QueryCount struct {
+	Query string `json:"query"`
+	Count int64  `json:"count"`
+}
+
+type FilterRate struct {
+	Searches   int64   `json:"searches"`
+	Restricted int64   `json:"restricted"`
+	Filtered   int64   `json:"filtered"`
+	Rate       float64 `json:"rate"`
+}
+
+func (d *DB) SaveQueryLogs(logs []QueryLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	return d.db.CreateInBatches(logs, 100).Error
+}
+
+func (d *DB) SaveClickLogs(logs []ClickLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	return d.db.CreateInBatches(logs, 100).Error
+}
+
+// ExperimentReport returns per-arm search, zero-result and click counts of
+// an experiment in [from, to). CTR is the share of searches with a click.
+func (d *DB) ExperimentReport(experiment string, from, to time.Time) ([]ArmReport, error) {
+	var arms []ArmReport
+	err := d.db.Model(&QueryLog{}).
+		Select("arm, COUNT(*) AS searches, "+
+			"COALESCE(SUM(CASE WHEN result_count = 0 THEN 1 ELSE 0 END), 0) AS zero_results").
+		Where("experiment = ? AND created_at >= ? AND created_at < ?", experiment, from, to).
+		Group("arm").
+		Order("arm").
+		Scan(&arms).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var clicks []struct {
+		Arm             string
+		ClickedSearches int64
+		Clicks          int64
+	}
+	err = d.db.Table("click_logs").
+		Select("query_logs.arm AS arm, COUNT(DISTINCT click_logs.search_id) AS clicked_searches, COUNT(*) AS clicks").
+		Joins("JOIN query_logs ON query_logs.search_id = click_logs.search_id").
+		Where("query_logs.experiment = ? AND query_logs.created_at >= ? AND query_logs.created_at < ?", experiment, from, to).
+		Group("query_logs.arm").
+		Scan(&clicks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range arms {
+		a := &arms[i]
+		for _, c := range clicks {
+			if c.Arm == a.Arm {
+				a.ClickedSearches = c.ClickedSearches
+				a.Clicks = c.Clicks
+			}
+		}
+		if a.Searches > 0 {
+			a.ZeroResultRate = float64(a.ZeroResults) / float64(a.Searches)
+			a.CTR = float64(a.ClickedSearches) / float64(a.Searches)
+		}
+	}
+	return arms, nil
+}
+
+// QueryDoc 查询词与其结果中被点击的文档
+type QueryDoc struct {
+	Query string
+	DocID string
+}
+
+// ClickedDocs returns the distinct (query, clicked document) pairs of
+// searches in [from, to).
+func (d *DB) ClickedDocs(from, to time.Time) ([]QueryDoc, error) {
+	var rows []QueryDoc
+	err := d.db.Table("click_logs").
+		Select("DISTINCT query_logs.query AS query, click_logs.doc_id AS doc_id").
+		Joins("JOIN query_logs ON query_logs.search_id = click_logs.search_id").
+		Where("query_logs.created_at >= ? AND query_logs.created_at < ?", from, to).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// TopQueries returns the most frequent queries in [from, to).
+func (d *DB) TopQueries(from, to time.Time, limit int) ([]QueryCount, error) {
+	var rows []QueryCount
+	err := d.db.Model(&QueryLog{}).
+		Select("query, COUNT(*) AS count").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("query").
+		Order("count DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ZeroResultQueries returns the most frequent queries without any result in [from, to).
+func (d *DB) ZeroResultQueries(from, to time.Time, limit int) ([]QueryCount, error) {
+	var rows []QueryCount
+	err := d.db.Model(&QueryLog{}).
+		Select("query, COUNT(*) AS count").
+		Where("created_at >= ? AND created_at < ? AND result_count = 0", from, to).
+		Group("query").
+		Order("count DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// LatencyPercentiles returns latency in milliseconds for each percentile
+// (0-100) in [from, to), keyed as "p50", "p95" and so on.
+func (d *DB) LatencyPercentiles(from, to time.Time, percentiles []float64) (map[string]int64, error) {
+	scope := d.db.Model(&QueryLog{}).Where("created_at >= ? AND created_at < ?", from, to)
+
+	var total int64
+	if err := scope.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]int64, len(percentiles))
+	if total == 0 {
+		return result, nil
+	}
+	for _, p := range percentiles {
+		// nearest-rank 方法
+		rank := int(math.Ceil(p / 100 * float64(total)))
+		if rank < 1 {
+			rank = 1
+		}
+		var latency int64
+		err := d.db.Model(&QueryLog{}).
+			Where("created_at >= ? AND created_at < ?", from, to).
+			Order("latency_ms").
+			Offset(rank-1).
+			Limit(1).
+			Pluck("latency_ms", &latency).Error
+		if err != nil {
+			return nil, err
+		}
+		result[fmt.Sprintf("p%g", p)] = latency
+	}
+	return result, nil
+}
+
+// FilterRate reports how many searches in [from, to) had results filtered.
+func (d *DB) FilterRate(from, to time.Time) (*FilterRate, error) {
+	var rate FilterRate
+	err := d.db.Model(&QueryLog{}).
+		Select("COUNT(*) AS searches, "+
+			"COALESCE(SUM(CASE WHEN restricted THEN 1 ELSE 0 END), 0) AS restricted, "+
+			"COALESCE(SUM(CASE WHEN filtered_count > 0 THEN 1 ELSE 0 END), 0) AS filtered").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Scan(&rate).Error
+	if err != nil {
+		return nil, err
+	}
+	if rate.Searches > 0 {
+		rate.Rate = float64(rate.Filtered) / float64(rate.Searches)
+	}
+	return &rate, nil
+}
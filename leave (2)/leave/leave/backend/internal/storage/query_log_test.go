@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDB(t *testing.T) *DB {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	return db
+}
+
+func TestQueryLogAnalytics(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+
+	var logs []QueryLog
+	for i := 1; i <= 10; i++ {
+		logs = append(logs, QueryLog{Query: "golang", ResultCount: 5, LatencyMs: int64(i * 10), CreatedAt: now})
+	}
+	logs = append(logs,
+		QueryLog{Query: "qwerty", ResultCount: 0, LatencyMs: 5, CreatedAt: now},
+		QueryLog{Query: "qwerty", ResultCount: 0, LatencyMs: 5, CreatedAt: now},
+		QueryLog{Query: "成人", ResultCount: 3, LatencyMs: 5, Restricted: true, FilteredCount: 2, CreatedAt: now},
+		// 时间范围外
+		QueryLog{Query: "old", ResultCount: 0, LatencyMs: 5, CreatedAt: now.Add(-48 * time.Hour)},
+	)
+	require.NoError(t, db.SaveQueryLogs(logs))
+
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+
+	top, err := db.TopQueries(from, to, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []QueryCount{{"golang", 10}, {"qwerty", 2}}, top)
+
+	zero, err := db.ZeroResultQueries(from, to, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []QueryCount{{"qwerty", 2}}, zero)
+
+	p, err := db.LatencyPercentiles(from, to, []float64{50, 99})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"p50": 40, "p99": 100}, p)
+
+	rate, err := db.FilterRate(from, to)
+	require.NoError(t, err)
+	assert.Equal(t, int64(13), rate.Searches)
+	assert.Equal(t, int64(1), rate.Restricted)
+	assert.Equal(t, int64(1), rate.Filtered)
+}
+
+func TestExperimentReport(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+
+	require.NoError(t, db.SaveQueryLogs([]QueryLog{
+		{SearchID: "s1", Query: "go", ResultCount: 3, Experiment: "exp", Arm: "control", CreatedAt: now},
+		{SearchID: "s2", Query: "go", ResultCount: 0, Experiment: "exp", Arm: "control", CreatedAt: now},
+		{SearchID: "s3", Query: "go", ResultCount: 3, Experiment: "exp", Arm: "treatment", CreatedAt: now},
+		{SearchID: "s4", Query: "go", ResultCount: 3, Experiment: "other", Arm: "treatment", CreatedAt: now},
+	}))
+	require.NoError(t, db.SaveClickLogs([]ClickLog{
+		{SearchID: "s1", DocID: "d1", CreatedAt: now},
+		{SearchID: "s3", DocID: "d1", CreatedAt: now},
+		{SearchID: "s3", DocID: "d2", CreatedAt: now},
+		{SearchID: "s4", DocID: "d1", CreatedAt: now},
+	}))
+
+	report, err := db.ExperimentReport("exp", now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []ArmReport{
+		{Arm: "control", Searches: 2, ZeroResults: 1, ZeroResultRate: 0.5, ClickedSearches: 1, Clicks: 1, CTR: 0.5},
+		{Arm: "treatment", Searches: 1, ClickedSearches: 1, Clicks: 2, CTR: 1},
+	}, report)
+}
@@ -13,6 +13,7 @@ interface SearchResult {
   crawlTime: string
   keywords: string[]
   snippet: string
+  pinned?: boolean
 }
 
 interface SearchResponse {
@@ -22,6 +23,17 @@ interface SearchResponse {
   took: number
   filtered?: boolean
   message?: string
+  legal_reference?: string
+  degraded?: boolean
+  relaxation?: string
+  relaxed_query?: string
+  search_id?: string
+  rewritten_query?: string
+  redirect?: string
+  refused?: boolean
+  safe_search?: string
+  safe_search_filtered?: number
+  total_estimated?: boolean
 }
 
 const SearchResultsPage: React.FC = () => {
@@ -33,7 +45,16 @@ const SearchResultsPage: React.FC = () => {
   const [error, setError] = useState('')
   const [currentPage, setCurrentPage] = useState(1)
   const [totalPages, setTotalPages] = useState(0)
+  const [totalEstimated, setTotalEstimated] = useState(false)
   const [filterMessage, setFilterMessage] = useState('')
+  const [legalReference, setLegalReference] = useState('')
+  const [degraded, setDegraded] = useState(false)
+  const [relaxedQuery, setRelaxedQuery] = useState('')
+  const [rewrittenQuery, setRewrittenQuery] = useState('')
+  const [relatedSearches, setRelatedSearches] = useState<string[]>([])
+  const [searchId, setSearchId] = useState('')
+  const [safeSearch, setSafeSearch] = useState('')
+  const [safeSearchFiltered, setSafeSearchFiltered] = useState(0)
 
   useEffect(() => {
     const q = searchParams.get('q') || ''
@@ -42,32 +63,54 @@ const SearchResultsPage: React.FC = () => {
     if (q) {
       setQuery(q)
       setCurrentPage(page)
-      search(q, page)
+      search(q, page, searchParams.get('safe') || '')
     } else {
       navigate('/')
     }
   }, [searchParams])
 
-  const search = async (searchQuery: string, page: number) => {
+  const search = async (searchQuery: string, page: number, safe: string) => {
     setLoading(true)
     setError('')
     setFilterMessage('')
+    setLegalReference('')
+    setDegraded(false)
+    setRelaxedQuery('')
+    setRewrittenQuery('')
     
     try {
       const response = await api.get('/search', {
         params: {
           q: searchQuery,
           page: page,
+          // 未指定时由后端按 cookie 决定安全搜索级别
+          safe: safe || undefined,
           size: 10
         }
       })
 
       const data: SearchResponse = response.data
+      // 导航类查询直接跳转
+      if (data.redirect) {
+        window.location.assign(data.redirect)
+        return
+      }
       setResults(data.hits || [])
       setTotalPages(Math.ceil(data.total / 10))
+      setTotalEstimated(!!data.total_estimated)
       
-      if (data.filtered && data.message) {
+      if ((data.filtered || data.refused) && data.message) {
         setFilterMessage(data.message)
+        setLegalReference(data.legal_reference || '')
+      }
+      setRewrittenQuery(data.rewritten_query || '')
+      setRelatedSearches(data.suggestions || [])
+      setDegraded(!!data.degraded)
+      setSearchId(data.search_id || '')
+      setSafeSearch(data.safe_search || '')
+      setSafeSearchFiltered(data.safe_search_filtered || 0)
+      if (data.relaxation && data.relaxed_query) {
+        setRelaxedQuery(data.relaxed_query)
       }
     } catch (err) {
       setError('搜索出错，请稍后重试')
@@ -77,16 +120,28 @@ const SearchResultsPage: React.FC = () => {
     }
   }
 
+  // 翻页和新搜索时保留用户选择的安全搜索级别
+  const safeParam = (): Record<string, string> => {
+    const safe = searchParams.get('safe')
+    return safe ? { safe } : {}
+  }
+
   const handleSearch = (newQuery?: string) => {
     const searchQuery = newQuery || query
     if (searchQuery.trim()) {
-      setSearchParams({ q: searchQuery.trim(), page: '1' })
+      setSearchParams({ q: searchQuery.trim(), page: '1', ...safeParam() })
+    }
+  }
+
+  const handleSafeSearchChange = (level: string) => {
+    if (query.trim()) {
+      setSearchParams({ q: query.trim(), page: '1', safe: level })
     }
   }
 
   const handlePageChange = (newPage: number) => {
     if (newPage >= 1 && newPage <= totalPages) {
-      setSearchParams({ q: query, page: newPage.toString() })
+      setSearchParams({ q: query, page: newPage.toString(), ...safeParam() })
     }
   }
 
@@ -96,6 +151,17 @@ const SearchResultsPage: React.FC = () => {
     }
   }
 
+  // 点击上报失败不影响跳转
+  const trackClick = (result: SearchResult, position: number) => {
+    if (!searchId) return
+    api.post('/click', {
+      search_id: searchId,
+      doc_id: result.id,
+      url: result.url,
+      position: (currentPage - 1) * 10 + position + 1
+    }).catch(() => {})
+  }
+
   const formatDate = (dateString: string) => {
     const date = new Date(dateString)
     const now = new Date()
@@ -175,6 +241,18 @@ const SearchResultsPage: React.FC = () => {
               <Search size={18} />
             </button>
           </div>
+
+          {/* 安全搜索级别 */}
+          <select
+            value={safeSearch}
+            onChange={(e) => handleSafeSearchChange(e.target.value)}
+            className="text-sm text-gray-600 bg-transparent border border-gray-200 rounded-full px-3 py-1.5 outline-none"
+            title="安全搜索"
+          >
+            <option value="off">安全搜索：关闭</option>
+            <option value="moderate">安全搜索：适中</option>
+            <option value="strict">安全搜索：严格</option>
+          </select>
         </div>
       </div>
 
@@ -190,23 +268,57 @@ const SearchResultsPage: React.FC = () => {
         {filterMessage && (
           <div className="bg-yellow-50 text-yellow-800 px-4 py-3 rounded-lg mb-6 text-sm border border-yellow-200">
             {filterMessage}
+            {legalReference && (
+              <span className="block mt-1 text-xs text-yellow-700">依据：{legalReference}</span>
+            )}
+          </div>
+        )}
+
+        {safeSearchFiltered > 0 && (
+          <div className="text-gray-500 mb-6 text-sm">
+            安全搜索已隐藏 {safeSearchFiltered} 条结果。
+          </div>
+        )}
+
+        {/* 降级提示信息 */}
+        {degraded && (
+          <div className="bg-gray-50 text-gray-600 px-4 py-3 rounded-lg mb-6 text-sm border border-gray-200">
+            搜索服务繁忙，当前显示的是稍早的缓存结果。
+          </div>
+        )}
+
+        {/* 查询改写提示 */}
+        {rewrittenQuery && (
+          <div className="text-sm text-gray-600 mb-6">
+            已为您搜索 <span className="font-medium text-gray-900">{rewrittenQuery}</span>
+          </div>
+        )}
+
+        {/* 放宽查询提示 */}
+        {relaxedQuery && (
+          <div className="text-sm text-gray-600 mb-6">
+            未找到与“{query}”完全匹配的结果，显示 <span className="font-medium text-gray-900">{relaxedQuery}</span> 的结果
           </div>
         )}
 
         {/* 搜索结果列表 */}
         <div className="space-y-8">
-          {results.map((result) => (
+          {results.map((result, index) => (
             <div key={result.id} className="group">
               <div className="flex items-center text-xs text-gray-500 mb-1.5 space-x-2">
                  <span className="font-medium text-gray-700">{result.domain}</span>
                  <span className="text-gray-300">•</span>
                  <span>{formatDate(result.crawlTime)}</span>
+                 {result.pinned && (
+                   <span className="px-1.5 py-0.5 rounded bg-blue-50 text-blue-700">官方推荐</span>
+                 )}
               </div>
               <h3 className="text-xl font-normal mb-2 leading-snug">
                 <a 
                   href={result.url} 
                   target="_blank" 
                   rel="noopener noreferrer"
+                  onClick={() => trackClick(result, index)}
                   className="text-blue-700 hover:underline decoration-blue-700/30"
                   dangerouslySetInnerHTML={{ 
                     __html: highlightText(result.title, query) 
@@ -231,6 +343,24 @@ const SearchResultsPage: React.FC = () => {
           </div>
         )}
 
+        {/* 相关搜索 */}
+        {!loading && relatedSearches.length > 0 && (
+          <div className="mt-12">
+            <h3 className="text-sm font-medium text-gray-900 mb-3">相关搜索</h3>
+            <div className="flex flex-wrap gap-2">
+              {relatedSearches.map((related) => (
+                <button
+                  key={related}
+                  onClick={() => handleSearch(related)}
+                  className="px-3 py-1.5 text-sm text-gray-700 bg-gray-50 rounded-full hover:bg-gray-100"
+                >
+                  {related}
+                </button>
+              ))}
+            </div>
+          </div>
+        )}
+
         {/* 分页 */}
         {totalPages > 1 && (
           <div className="flex justify-center items-center space-x-2 mt-12 mb-12">
@@ -244,7 +374,7 @@ const SearchResultsPage: React.FC = () => {
             
             <div className="flex items-center space-x-1">
               <span className="text-sm text-gray-500">
-                第 {currentPage} 页 / 共 {totalPages} 页
+                第 {currentPage} 页 / {totalEstimated ? '约' : '共'} {totalPages} 页
               </span>
             </div>
             